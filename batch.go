@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cuotos/chip8/chip"
+	"github.com/cuotos/chip8/utils"
+)
+
+// batchResult is how a single ROM ended up, one row of the batch report
+type batchResult struct {
+	ROM            string   `json:"rom"`
+	Status         string   `json:"status"` // halted, running, waiting-key, fault or error
+	Frames         int      `json:"frames"`
+	Cycles         uint64   `json:"cycles"`
+	FrameHash      string   `json:"frame_hash,omitempty"` // see gfx.Framebuffer.Hash
+	Fault          string   `json:"fault,omitempty"`
+	UnknownOpcodes []string `json:"unknown_opcodes,omitempty"`
+}
+
+// runBatch is the batch subcommand: chip8 batch [-frames N] [-workers N] [-format json|csv] [-o file] dir
+// It runs every .ch8 under dir headlessly, each on its own chip spread over a pool of workers, and writes a report of
+// how each one ended up. The random numbers are seeded the same for every ROM so runs can be compared.
+func runBatch(args []string) int {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	frames := flags.Int("frames", 600, "run each ROM for this many frames, unless it halts first")
+	workers := flags.Int("workers", runtime.NumCPU(), "how many ROMs to run at once")
+	format := flags.String("format", "json", "format of the report, json or csv")
+	outPath := flags.String("o", "", "write the report to this file rather than stdout")
+	seed := flags.Int64("seed", 1, "seed for the random numbers of every ROM")
+	vblank := flags.Bool("vblank", false, vblankHelp)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || *workers < 1 || (*format != "json" && *format != "csv") {
+		fmt.Println("usage: chip8 batch [-frames N] [-workers N] [-format json|csv] [-o file] [-seed N] [-vblank] <dir>")
+		return 2
+	}
+
+	var roms []string
+	err := filepath.WalkDir(flags.Arg(0), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".ch8") {
+			roms = append(roms, path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Print("[ERROR] ", err)
+		return 2
+	}
+	sort.Strings(roms)
+
+	results := make([]batchResult, len(roms))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = batchROM(flags, settingsFlags{Vblank: vblank}, roms[i], *frames, *seed)
+			}
+		}()
+	}
+	for i := range roms {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	out := io.Writer(os.Stdout)
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			log.Print("[ERROR] ", err)
+			return 2
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if *format == "csv" {
+		err = writeBatchCSV(out, results)
+	} else {
+		err = writeBatchJSON(out, results)
+	}
+	if err != nil {
+		log.Print("[ERROR] ", err)
+		return 2
+	}
+
+	status := 0
+	for _, r := range results {
+		if r.Status == "fault" || r.Status == "error" {
+			status = 1
+		}
+	}
+	log.Printf("[INFO] ran %d ROMs", len(results))
+	return status
+}
+
+// batchROM runs a single ROM on a chip of its own. Unknown opcodes are noted and skipped so the rest of the ROM still
+// gets a run, any other fault stops it.
+func batchROM(fs *flag.FlagSet, flags settingsFlags, path string, frames int, seed int64) batchResult {
+	result := batchResult{ROM: path}
+
+	settings, err := loadSettings(fs, flags, path)
+	if err != nil {
+		result.Status, result.Fault = "error", err.Error()
+		return result
+	}
+
+	c := chip.New(
+		chip.WithQuirks(chip.Quirks{DisplayWait: settings.DisplayWait}),
+		chip.WithSeed(seed),
+	)
+	if err := c.Load(path); err != nil {
+		result.Status, result.Fault = "error", err.Error()
+		return result
+	}
+
+	unknown := map[uint16]bool{}
+	result.Status = "running"
+frames:
+	for ; result.Frames < frames; result.Frames++ {
+		if c.Idle == chip.IdleHalted {
+			result.Status = "halted"
+			break
+		}
+		if c.Idle == chip.IdleWaitKey {
+			result.Status = "waiting-key"
+			break
+		}
+
+		for i := 0; i < chip.CyclesPerFrame && c.Idle == chip.NotIdle; i++ {
+			err := c.EmulateCycle()
+			// 0000 is nearly always empty memory, the ROM has run off the end of its code, so that's still a fault
+			if errors.Is(err, utils.UnkownOpcode) {
+				unknown[c.OpCode] = true
+				if c.OpCode != 0x0000 {
+					c.PC += 2
+					continue
+				}
+			}
+			if err != nil {
+				result.Status, result.Fault = "fault", err.Error()
+				break frames
+			}
+		}
+		c.TickTimers()
+	}
+
+	result.Cycles = c.Cycles
+	result.FrameHash = c.Display.Hash()
+	for op := range unknown {
+		result.UnknownOpcodes = append(result.UnknownOpcodes, fmt.Sprintf("%04x", op))
+	}
+	sort.Strings(result.UnknownOpcodes)
+
+	return result
+}
+
+func writeBatchJSON(w io.Writer, results []batchResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// writeBatchCSV writes a row per ROM, any unknown opcodes separated by spaces
+func writeBatchCSV(w io.Writer, results []batchResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"rom", "status", "frames", "cycles", "frame_hash", "fault", "unknown_opcodes"})
+	for _, r := range results {
+		cw.Write([]string{
+			r.ROM,
+			r.Status,
+			strconv.Itoa(r.Frames),
+			strconv.FormatUint(r.Cycles, 10),
+			r.FrameHash,
+			r.Fault,
+			strings.Join(r.UnknownOpcodes, " "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchTestROM runs a ROM through batchROM with no flags set, as the batch subcommand would
+func batchTestROM(t *testing.T, path string, frames int, seed int64) batchResult {
+	t.Helper()
+	return batchROM(flag.NewFlagSet("batch", flag.ContinueOnError), settingsFlags{}, path, frames, seed)
+}
+
+// writeTestROM writes a ROM to a temporary .ch8 file and returns its path
+func writeTestROM(t *testing.T, name string, rom []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name+".ch8")
+	require.NoError(t, os.WriteFile(path, rom, 0644))
+	return path
+}
+
+func TestBatchROMs(t *testing.T) {
+	tcs := []struct {
+		ROM     string
+		Status  string
+		Unknown []string
+	}{
+		{"bc_test", "halted", nil},
+		{"pong", "running", nil},
+		{"invaders", "running", nil},
+		// jumps into the font, skips F090 and runs on until it reaches empty memory
+		{"test_rom", "fault", []string{"0000", "f090"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.ROM, func(t *testing.T) {
+			r := batchTestROM(t, filepath.Join("roms", tc.ROM+".ch8"), 120, 1)
+			assert.Equal(t, tc.Status, r.Status, r.Fault)
+			assert.Equal(t, tc.Unknown, r.UnknownOpcodes)
+			assert.NotEmpty(t, r.FrameHash)
+			assert.Greater(t, r.Cycles, uint64(0))
+			if tc.Status == "running" {
+				assert.Equal(t, 120, r.Frames)
+			}
+		})
+	}
+}
+
+func TestBatchStatus(t *testing.T) {
+	tcs := []struct {
+		Name    string
+		ROM     []byte
+		Status  string
+		Unknown []string
+	}{
+		// unknown opcodes are skipped and the ROM carries on to the jump to itself
+		{"unknown opcodes", []byte{0x01, 0x23, 0xe0, 0xff, 0x60, 0x05, 0x01, 0x23, 0x12, 0x08}, "halted", []string{"0123", "e0ff"}},
+		// 0000 is still a fault, it's nearly always the end of the ROM
+		{"empty memory", []byte{0x60, 0x01}, "fault", []string{"0000"}},
+		{"wait for a key", []byte{0xf0, 0x0a}, "waiting-key", nil},
+		{"missing file", nil, "error", nil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "missing.ch8")
+			if tc.ROM != nil {
+				path = writeTestROM(t, "rom", tc.ROM)
+			}
+
+			r := batchTestROM(t, path, 60, 1)
+			assert.Equal(t, tc.Status, r.Status, r.Fault)
+			assert.Equal(t, tc.Unknown, r.UnknownOpcodes)
+			if tc.Status == "fault" || tc.Status == "error" {
+				assert.NotEmpty(t, r.Fault)
+			} else {
+				assert.Empty(t, r.Fault)
+			}
+		})
+	}
+}
+
+func TestBatchSeed(t *testing.T) {
+	// draw the font sprite for a random digit at a random position, forever
+	path := writeTestROM(t, "random", []byte{
+		0xc0, 0x3f, // V0 = random x
+		0xc1, 0x1f, // V1 = random y
+		0xc2, 0x0f, // V2 = random digit
+		0xf2, 0x29, // I = sprite for V2
+		0xd0, 0x15,
+		0x12, 0x00,
+	})
+
+	a := batchTestROM(t, path, 30, 1)
+	require.Equal(t, "running", a.Status, a.Fault)
+	assert.Equal(t, a, batchTestROM(t, path, 30, 1), "the same seed draws the same screen")
+	assert.NotEqual(t, a.FrameHash, batchTestROM(t, path, 30, 2).FrameHash)
+}
+
+func TestBatchReportRoundTrip(t *testing.T) {
+	results := []batchResult{
+		{ROM: "roms/a.ch8", Status: "halted", Frames: 25, Cycles: 198, FrameHash: "8b849e2d23df780c"},
+		{ROM: "roms/b, with a comma.ch8", Status: "fault", Frames: 10, Cycles: 87, FrameHash: "a1e302fe8e1a9b86",
+			Fault: "unable to lookup opcode: 0000: unknown opcode", UnknownOpcodes: []string{"0000", "0123"}},
+		{ROM: "roms/c.ch8", Status: "error", Fault: "open roms/c.ch8: no such file or directory"},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeBatchJSON(&buf, results))
+
+		var got []batchResult
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, results, got)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeBatchCSV(&buf, results))
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, len(results)+1)
+		assert.Equal(t, []string{"rom", "status", "frames", "cycles", "frame_hash", "fault", "unknown_opcodes"}, rows[0])
+
+		var got []batchResult
+		for _, row := range rows[1:] {
+			frames, err := strconv.Atoi(row[2])
+			require.NoError(t, err)
+			cycles, err := strconv.ParseUint(row[3], 10, 64)
+			require.NoError(t, err)
+			r := batchResult{ROM: row[0], Status: row[1], Frames: frames, Cycles: cycles, FrameHash: row[4], Fault: row[5]}
+			if row[6] != "" {
+				r.UnknownOpcodes = strings.Split(row[6], " ")
+			}
+			got = append(got, r)
+		}
+		assert.Equal(t, results, got)
+	})
+}
+
+func TestRunBatch(t *testing.T) {
+	dir := t.TempDir()
+	writeROM := func(name string, rom []byte) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), rom, 0644))
+	}
+	writeROM("b.ch8", []byte{0x12, 0x00})
+	writeROM("a.CH8", []byte{0xf0, 0x0a})
+	writeROM("notes.txt", []byte("not a ROM"))
+	out := filepath.Join(dir, "report.json")
+
+	// the same report however many workers there are
+	var reports [][]byte
+	for _, workers := range []string{"1", "4"} {
+		require.Equal(t, 0, runBatch([]string{"-frames", "10", "-workers", workers, "-o", out, dir}))
+		report, err := os.ReadFile(out)
+		require.NoError(t, err)
+		reports = append(reports, report)
+	}
+	assert.Equal(t, string(reports[0]), string(reports[1]))
+
+	var results []batchResult
+	require.NoError(t, json.Unmarshal(reports[0], &results))
+	require.Len(t, results, 2, "only .ch8 files are run")
+	assert.Equal(t, "waiting-key", results[0].Status)
+	assert.Equal(t, "halted", results[1].Status)
+
+	// a fault fails the run
+	writeROM("c.ch8", []byte{0x00, 0xee})
+	assert.Equal(t, 1, runBatch([]string{"-frames", "10", "-o", out, dir}))
+}
@@ -0,0 +1,124 @@
+package chip
+
+import (
+	"github.com/cuotos/chip8/gfx"
+)
+
+// State is a copy of the registers, timers and keypad, see Chip8.State
+type State struct {
+	OpCode     uint16 // the last instruction fetched
+	PC         uint16
+	I          uint16
+	V          [16]uint8
+	SP         uint16
+	Stack      [16]uint16
+	DelayTimer uint8
+	SoundTimer uint8
+	Keypad     [16]bool
+	Cycles     uint64
+	Idle       IdleState
+}
+
+// Reset puts the chip back to how it was when it was turned on. Registers, the stack, timers, keypad and display are
+// all cleared, as is memory other than the font. A soft reset then loads the last ROM again so it starts from the
+// beginning, undoing anything it wrote to memory, a hard reset leaves memory empty for another ROM to be loaded.
+// Quirks, options and custom opcodes are kept.
+func (c *Chip8) Reset(hard bool) {
+	c.Memory = [4096]uint8{}
+	c.V = [16]uint8{}
+	c.Stack = [16]uint16{}
+	c.DelayTimer = 0
+	c.SoundTimer = 0
+	c.Keypad = [16]uint8{}
+	c.Display.Clear()
+	c.DrawFlag = true
+	c.err = nil
+	c.Initialise()
+	c.updateAudio()
+
+	if hard {
+		c.rom = nil
+	}
+	for i, b := range c.rom {
+		c.Memory[0x200+i] = b
+	}
+	c.InvalidateInstructionCache()
+}
+
+// Step executes a single instruction
+func (c *Chip8) Step() error {
+	return c.EmulateCycle()
+}
+
+// RunFrame runs one 60th of a second: it polls the input, executes the instructions for a frame, stopping early if
+// the ROM is idle, ticks the timers and presents the screen if it's been drawn to
+func (c *Chip8) RunFrame() error {
+	if c.input != nil {
+		for key, down := range c.input.Keys() {
+			c.SetKey(uint8(key), down)
+		}
+	}
+
+	for i := 0; i < c.cyclesPerFrame && c.Idle == NotIdle; i++ {
+		if err := c.Step(); err != nil {
+			return err
+		}
+	}
+	c.TickTimers()
+
+	if c.display != nil && c.DrawFlag {
+		c.display.Present(c.Display)
+		c.DrawFlag = false
+	}
+
+	return nil
+}
+
+// State is a copy of the registers, timers and keypad, changing it doesn't change the chip
+func (c *Chip8) State() State {
+	s := State{
+		OpCode:     c.OpCode,
+		PC:         c.PC,
+		I:          c.I,
+		V:          c.V,
+		SP:         c.SP,
+		Stack:      c.Stack,
+		DelayTimer: c.DelayTimer,
+		SoundTimer: c.SoundTimer,
+		Cycles:     c.Cycles,
+		Idle:       c.Idle,
+	}
+	for i, v := range c.Keypad {
+		s.Keypad[i] = v != 0
+	}
+	return s
+}
+
+// ReadMemory copies up to n bytes of memory starting at addr, fewer if that runs past the end
+func (c *Chip8) ReadMemory(addr uint16, n int) []byte {
+	if int(addr) >= len(c.Memory) || n <= 0 {
+		return nil
+	}
+	end := int(addr) + n
+	if end > len(c.Memory) {
+		end = len(c.Memory)
+	}
+	return append([]byte(nil), c.Memory[addr:end]...)
+}
+
+// Screen is a copy of the display
+func (c *Chip8) Screen() *gfx.Framebuffer {
+	f := &gfx.Framebuffer{}
+	f.CopyFrom(c.Display)
+	return f
+}
+
+// updateAudio tells the audio when the sound timer starts or stops
+func (c *Chip8) updateAudio() {
+	on := c.SoundTimer > 0
+	if c.audio == nil || on == c.beeping {
+		return
+	}
+	c.beeping = on
+	c.audio.Beep(on)
+}
@@ -0,0 +1,88 @@
+package chip
+
+import (
+	"fmt"
+
+	"github.com/cuotos/chip8/utils"
+)
+
+// decodedInstruction is the result of fetching and looking up the opcode at a single address
+type decodedInstruction struct {
+	valid   bool
+	opcode  uint16
+	handler func(*Chip8)
+}
+
+// instructionCache holds a decoded instruction per address in Memory. An entry is only valid for as long as the two
+// bytes it was decoded from are unchanged, so anything writing to Memory while the cache is enabled must go through
+// invalidate (FX33, FX55 and WriteMemory do this).
+type instructionCache struct {
+	entries [4096]decodedInstruction
+}
+
+// invalidate drops any entry decoded from the byte at addr. Instructions are 2 bytes wide so this is the entry
+// starting at addr and the one starting just before it.
+func (ic *instructionCache) invalidate(addr uint16) {
+	ic.entries[addr%4096].valid = false
+	ic.entries[(addr+4095)%4096].valid = false
+}
+
+func (ic *instructionCache) invalidateAll() {
+	for i := range ic.entries {
+		ic.entries[i].valid = false
+	}
+}
+
+// EnableInstructionCache turns on caching of decoded instructions per address. It is intended for bulk headless runs
+// where the cost of fetch/decode on every cycle adds up. Memory written directly rather than through WriteMemory is
+// not tracked, so call InvalidateInstructionCache after doing so.
+func (c *Chip8) EnableInstructionCache() {
+	c.cache = &instructionCache{}
+}
+
+// InvalidateInstructionCache drops every cached instruction, it is a no-op if the cache is not enabled.
+func (c *Chip8) InvalidateInstructionCache() {
+	if c.cache != nil {
+		c.cache.invalidateAll()
+	}
+}
+
+// WriteMemory sets a byte of Memory, keeping the instruction cache in step. Debuggers and tools poking at a running
+// chip should use this rather than writing to Memory directly.
+func (c *Chip8) WriteMemory(addr uint16, value uint8) {
+	c.Memory[addr] = value
+	if c.cache != nil {
+		c.cache.invalidate(addr)
+	}
+}
+
+// fetch reads the opcode at PC into OpCode and returns the function that handles it, using the cache if enabled.
+func (c *Chip8) fetch() (func(*Chip8), error) {
+	if int(c.PC)+1 >= len(c.Memory) {
+		return nil, fmt.Errorf("%w: fetch from %04x", utils.MemoryOutOfRange, c.PC)
+	}
+
+	if c.cache != nil {
+		if e := &c.cache.entries[c.PC]; e.valid {
+			c.OpCode = e.opcode
+			return e.handler, nil
+		}
+	}
+
+	c.OpCode = uint16(c.Memory[c.PC])<<8 | uint16(c.Memory[c.PC+1])
+
+	f, err := c.lookup(c.OpCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cache != nil {
+		c.cache.entries[c.PC] = decodedInstruction{
+			valid:   true,
+			opcode:  c.OpCode,
+			handler: f,
+		}
+	}
+
+	return f, nil
+}
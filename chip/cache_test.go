@@ -0,0 +1,144 @@
+package chip
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// selfModifyingProgram calls a subroutine at 0x20c, then rewrites the first instruction of that subroutine with FX55
+// and calls it again. The first run sets VA to 1, the second must set it to 2.
+var selfModifyingProgram = []uint8{
+	0x22, 0x0c, // 200: call 0x20c
+	0x60, 0x6a, // 202: V0 = 0x6a
+	0x61, 0x02, // 204: V1 = 0x02
+	0xa2, 0x0c, // 206: I = 0x20c
+	0xf1, 0x55, // 208: mem[I..I+1] = V0..V1, the subroutine becomes "6a02"
+	0x22, 0x0c, // 20a: call 0x20c
+	0x6a, 0x01, // 20c: VA = 1
+	0x00, 0xee, // 20e: return
+}
+
+func loadProgram(c *Chip8, program []uint8) {
+	c.Initialise()
+	for i, b := range program {
+		c.Memory[0x200+i] = b
+	}
+}
+
+func TestSelfModifyingCodeWithCache(t *testing.T) {
+	tcs := []struct {
+		Name  string
+		Cache bool
+	}{
+		{"uncached", false},
+		{"cached", true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := NewDefaultChip()
+			if tc.Cache {
+				c.EnableInstructionCache()
+			}
+			loadProgram(c, selfModifyingProgram)
+
+			// run as far as the first return, the subroutine has now been decoded and cached
+			for i := 0; i < 3; i++ {
+				assert.NoError(t, c.EmulateCycle())
+			}
+			assert.Equal(t, uint8(1), c.V[0xa])
+
+			for i := 0; i < 6; i++ {
+				assert.NoError(t, c.EmulateCycle())
+			}
+			assert.Equal(t, uint16(0x20e), c.PC)
+			assert.Equal(t, uint16(0x6a02), c.OpCode)
+			assert.Equal(t, uint8(2), c.V[0xa])
+		})
+	}
+}
+
+func TestCacheInvalidatedByFX33(t *testing.T) {
+	c := NewDefaultChip()
+	c.EnableInstructionCache()
+	loadProgram(c, []uint8{
+		0x6a, 0x01, // 200: VA = 1
+		0x60, 0x7b, // 202: V0 = 123
+		0xa2, 0x00, // 204: I = 0x200
+		0xf0, 0x33, // 206: mem[0x200..0x202] = 1, 2, 3
+	})
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, c.EmulateCycle())
+	}
+
+	// 0x200 now holds 0x0102, which isn't an opcode this chip knows. If the stale "6a01" was still cached this
+	// would succeed.
+	c.PC = 0x200
+	assert.Error(t, c.EmulateCycle())
+}
+
+func TestCacheInvalidatedByWriteMemory(t *testing.T) {
+	c := NewDefaultChip()
+	c.EnableInstructionCache()
+	loadProgram(c, []uint8{
+		0x6a, 0x01, // 200: VA = 1
+	})
+
+	assert.NoError(t, c.EmulateCycle())
+	assert.Equal(t, uint8(1), c.V[0xa])
+
+	// poke the second byte only, the instruction starting the byte before must still be dropped
+	c.WriteMemory(0x201, 0x05)
+	c.PC = 0x200
+	assert.NoError(t, c.EmulateCycle())
+	assert.Equal(t, uint8(5), c.V[0xa])
+}
+
+func TestInvalidateInstructionCache(t *testing.T) {
+	c := NewDefaultChip()
+	c.EnableInstructionCache()
+	loadProgram(c, []uint8{
+		0x6a, 0x01, // 200: VA = 1
+	})
+	assert.NoError(t, c.EmulateCycle())
+
+	c.Memory[0x201] = 0x07
+	c.InvalidateInstructionCache()
+	c.PC = 0x200
+	assert.NoError(t, c.EmulateCycle())
+	assert.Equal(t, uint8(7), c.V[0xa])
+}
+
+// benchmarkEmulateCycle runs a real ROM through EmulateCycle, ticking the timers every frame as the frontends do. The
+// ROM is restarted if it ever stops, so every iteration is a whole instruction.
+func benchmarkEmulateCycle(b *testing.B, cached bool) {
+	c := NewDefaultChip()
+	c.Initialise()
+	if cached {
+		c.EnableInstructionCache()
+	}
+	if err := c.Load(filepath.Join("..", "roms", "invaders.ch8")); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%CyclesPerFrame == 0 {
+			c.TickTimers()
+		}
+		if err := c.EmulateCycle(); err != nil {
+			c.Reset(false)
+		}
+	}
+}
+
+func BenchmarkEmulateCycle(b *testing.B) {
+	benchmarkEmulateCycle(b, false)
+}
+
+func BenchmarkEmulateCycleCached(b *testing.B) {
+	benchmarkEmulateCycle(b, true)
+}
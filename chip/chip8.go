@@ -1,15 +1,25 @@
 package chip
 
 import (
-	"log"
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"fmt"
 	"math/rand"
 	"os"
+	"time"
 
 	"github.com/cuotos/chip8/gfx"
+	"github.com/cuotos/chip8/utils"
 )
 
 const (
 	VF = 0xf
+
+	// CyclesPerFrame is how many instructions run for each 60Hz tick of the timers, roughly 500Hz
+	CyclesPerFrame = 8
+
+	// MaxROMSize is the most that fits in memory after 0x200, where ROMs are loaded
+	MaxROMSize = 4096 - 0x200
 )
 
 type randomUintFunc func() uint8
@@ -26,9 +36,26 @@ type Chip8 struct {
 	SP             uint16
 	DrawFlag       bool
 	Keypad         [16]uint8
-	GFX            gfx.GFX
-	opcodes        // map of the opcode, can be replaced for testing
+	KeysPolled     uint16           // a bit for each key checked by EX9E, EXA1 or FX0A, for the frontend to clear
+	Display        *gfx.Framebuffer // the screen, for the frontend to present whenever DrawFlag is set
+	Cycles         uint64           // number of instructions executed
+	Tracer         *Tracer          // if set, every executed instruction is written to the trace
+	Quirks         Quirks
+	Idle           IdleState
+	OnIdle         func(IdleState) // called whenever Idle changes
+	opcodes                        // map of the opcode, can be replaced for testing
 	randomUintFunc randomUintFunc
+	cache          *instructionCache // nil unless EnableInstructionCache has been called
+	err            error             // fault raised by the instruction being executed
+	idle           idleDetector
+	vblank         bool           // set by each timer tick, see Quirks.DisplayWait
+	custom         []customOpcode // see RegisterOpcode
+	cyclesPerFrame int            // instructions run by RunFrame
+	display        gfx.GFX
+	audio          Audio
+	input          Input
+	beeping        bool   // whether audio was last told to beep
+	rom            []byte // the last ROM loaded, for a soft Reset to restart
 }
 
 func NewDefaultChip() *Chip8 {
@@ -39,6 +66,8 @@ func NewChip8(opcodes opcodes, randomiser randomUintFunc) *Chip8 {
 	c := &Chip8{
 		opcodes:        opcodes,
 		randomUintFunc: randomiser,
+		Display:        gfx.NewFramebuffer(gfx.Width, gfx.Height),
+		cyclesPerFrame: CyclesPerFrame,
 	}
 
 	if c.opcodes == nil {
@@ -46,20 +75,37 @@ func NewChip8(opcodes opcodes, randomiser randomUintFunc) *Chip8 {
 	}
 
 	if c.randomUintFunc == nil {
-		c.randomUintFunc = func() uint8 {
-			return uint8(rand.Int31n(255))
+		// each chip has its own source, seeded from the OS rather than the clock so chips made in the same tick differ
+		var seed [8]byte
+		if _, err := cryptorand.Read(seed[:]); err != nil {
+			binary.LittleEndian.PutUint64(seed[:], uint64(time.Now().UnixNano()))
 		}
+		c.randomUintFunc = seededRandom(int64(binary.LittleEndian.Uint64(seed[:])))
 	}
 
 	return c
 }
 
+// seededRandom returns every value from 0 to 255, the same ones in the same order for the same seed
+func seededRandom(seed int64) randomUintFunc {
+	r := rand.New(rand.NewSource(seed))
+	return func() uint8 {
+		return uint8(r.Intn(256))
+	}
+}
+
 func (c *Chip8) Initialise() {
 	// program counter starts at 0x200
 	c.PC = 0x200
 	c.OpCode = 0
 	c.I = 0
 	c.SP = 0
+	c.Cycles = 0
+	c.Idle = NotIdle
+	c.idle = idleDetector{}
+	c.Display.Resize(gfx.Width, gfx.Height)
+	c.vblank = false
+	c.KeysPolled = 0
 
 	// Load fontset
 	for i := 0; i < len(FontSet); i++ {
@@ -87,25 +133,90 @@ func (c *Chip8) Load(filename string) error {
 		return err
 	}
 
-	for i := 0; i < len(buffer); i++ {
-		c.Memory[i+512] = buffer[i]
+	return c.LoadROM(buffer)
+}
+
+// CheckROM returns an error if the ROM can't be loaded, without touching a chip. LoadROM checks it as well.
+func CheckROM(rom []byte) error {
+	if len(rom) > MaxROMSize {
+		return fmt.Errorf("%w: ROM is %d bytes, only %d will fit", utils.MemoryOutOfRange, len(rom), MaxROMSize)
+	}
+	return nil
+}
+
+// LoadROM copies a ROM image into memory at 0x200
+func (c *Chip8) LoadROM(rom []byte) error {
+	if err := CheckROM(rom); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(rom); i++ {
+		c.Memory[i+512] = rom[i]
 	}
+	c.rom = append(c.rom[:0], rom...)
+	c.InvalidateInstructionCache()
 
 	return nil
 }
 
 func (c *Chip8) EmulateCycle() error {
 
-	// Fetch opcode
-	c.OpCode = uint16(c.Memory[c.PC])<<8 | uint16(c.Memory[c.PC+1])
-	log.Printf("[DEBUG] oc:%04x pc:%x I:%02x\n", c.OpCode, c.PC, c.I)
-	c.DiagDump()
-	err := c.HandleOpcode()
+	// Fetch and decode opcode
+	f, err := c.fetch()
 	if err != nil {
 		return err
 	}
 
+	if c.Tracer != nil {
+		if err := c.Tracer.Trace(c.traceRecord()); err != nil {
+			return err
+		}
+	}
+
+	pc := c.PC
+	f(c)
+	if err := c.checkFault(); err != nil {
+		return err
+	}
+	c.Cycles++
+	c.detectIdle(pc)
+	c.updateAudio()
+
 	return nil
 }
 
+// fault stops the current instruction, the error is returned from EmulateCycle or HandleOpcode. The instruction
+// handler must return straight after raising it.
+func (c *Chip8) fault(err error, format string, args ...interface{}) {
+	c.err = fmt.Errorf("%w: %s (oc:%04x pc:%03x)", err, fmt.Sprintf(format, args...), c.OpCode, c.PC)
+}
+
+// checkFault returns and clears any fault raised by the last instruction. A PC that has been moved outside of memory
+// (a jump, skip or just running off the end) is a fault too, rather than waiting for the next fetch to trip over it.
+func (c *Chip8) checkFault() error {
+	if c.err == nil && int(c.PC)+1 >= len(c.Memory) {
+		c.fault(utils.MemoryOutOfRange, "PC moved to %04x", c.PC)
+	}
+
+	err := c.err
+	c.err = nil
+	return err
+}
+
+// TickTimers decrements the delay and sound timers, it should be called at 60Hz
+func (c *Chip8) TickTimers() {
+	if c.DelayTimer > 0 {
+		c.DelayTimer -= 1
+	}
+	if c.SoundTimer > 0 {
+		c.SoundTimer -= 1
+	}
+	c.vblank = true
+	c.updateAudio()
+
+	if c.Idle == IdleWaitTimer {
+		c.setIdle(NotIdle)
+	}
+}
+
 func (c *Chip8) SetKeys() {}
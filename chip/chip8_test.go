@@ -1,7 +1,11 @@
 package chip
 
 import (
+	"errors"
+	"github.com/cuotos/chip8/gfx"
+	"github.com/cuotos/chip8/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 )
 
@@ -41,17 +45,9 @@ func TestCountersDecrementOnCycle(t *testing.T) {
 	}
 }
 
-type mockGFX struct{}
-func (g mockGFX) Initialise() (func(), error) {return nil, nil}
-func (g mockGFX) SetPixel(pixel, value uint16) {}
-func (g mockGFX) GetPixel(pixel uint16) uint16 {return 0}
-func (mockGFX) Draw(){}
-func (mockGFX) Clear(){}
-
 func TestDrawFlagIsResetAfterADraw(t *testing.T) {
 	t.Skip()
 	c := NewChip8(opcodes{0x0000: func(c *Chip8) {}}, nil) //NOOP
-	c.GFX = &mockGFX{}
 
 	c.DrawFlag = true
 
@@ -108,7 +104,144 @@ func TestNewChip8(t *testing.T) {
 
 }
 
-//TODO: Test Initialise
+func TestRandomNumbers(t *testing.T) {
+	draw := func(c *Chip8) []uint8 {
+		var values []uint8
+		for i := 0; i < 4096; i++ {
+			values = append(values, c.randomUintFunc())
+		}
+		return values
+	}
+
+	// the same seed gives the same numbers, and all of them can come up
+	a, b := draw(New(WithSeed(7))), draw(New(WithSeed(7)))
+	assert.Equal(t, a, b)
+	assert.Contains(t, a, uint8(0))
+	assert.Contains(t, a, uint8(255))
+	assert.NotEqual(t, a, draw(New(WithSeed(8))))
+
+	// chips made one after the other don't share a seed
+	assert.NotEqual(t, draw(New()), draw(New()))
+}
+
 func TestInitialiseTheChip(t *testing.T) {
-	TODO(t)
+	c := NewDefaultChip()
+	c.Initialise()
+
+	assert.Equal(t, uint16(0x200), c.PC)
+	assert.Equal(t, uint16(0), c.OpCode)
+	assert.Equal(t, uint16(0), c.I)
+	assert.Equal(t, uint16(0), c.SP)
+	assert.Equal(t, uint64(0), c.Cycles)
+	assert.Equal(t, NotIdle, c.Idle)
+	assert.Equal(t, uint16(0), c.KeysPolled)
+	assert.Equal(t, gfx.Width, c.Display.Width)
+	assert.Equal(t, gfx.Height, c.Display.Height)
+	assert.Equal(t, FontSet[:], c.Memory[:len(FontSet)])
+}
+
+// dirtyChip is a chip part way through running a ROM, with every bit of state changed from its starting value
+func dirtyChip(t *testing.T) *Chip8 {
+	c := NewDefaultChip()
+	c.Initialise()
+	c.Quirks.WrapSprites = true
+	require.NoError(t, c.LoadROM([]byte{0x12, 0x34, 0x56}))
+
+	c.Memory[0x200] = 0xff // the ROM overwrote itself
+	c.Memory[0x800] = 0xaa
+	c.Memory[0] = 0 // and the font
+	c.V[0x3] = 0x33
+	c.V[VF] = 1
+	c.I = 0x456
+	c.PC = 0x678
+	c.OpCode = 0x1234
+	c.Stack[0] = 0x202
+	c.SP = 1
+	c.DelayTimer = 10
+	c.SoundTimer = 20
+	c.Keypad[0x5] = 1
+	c.KeysPolled = 0xffff
+	c.Display.Resize(gfx.HiResWidth, gfx.HiResHeight)
+	c.Display.Set(1, 2, true)
+	c.DrawFlag = false
+	c.Cycles = 1000
+	c.Idle = IdleWaitKey
+	return c
+}
+
+// assertReset checks everything but memory above the font is back to how it starts
+func assertReset(t *testing.T, c *Chip8) {
+	t.Helper()
+	assert.Equal(t, uint16(0x200), c.PC)
+	assert.Equal(t, uint16(0), c.OpCode)
+	assert.Equal(t, uint16(0), c.I)
+	assert.Equal(t, [16]uint8{}, c.V)
+	assert.Equal(t, [16]uint16{}, c.Stack)
+	assert.Equal(t, uint16(0), c.SP)
+	assert.Equal(t, uint8(0), c.DelayTimer)
+	assert.Equal(t, uint8(0), c.SoundTimer)
+	assert.Equal(t, [16]uint8{}, c.Keypad)
+	assert.Equal(t, uint16(0), c.KeysPolled)
+	assert.Equal(t, gfx.NewFramebuffer(gfx.Width, gfx.Height), c.Display)
+	assert.True(t, c.DrawFlag, "the cleared display needs presenting")
+	assert.Equal(t, uint64(0), c.Cycles)
+	assert.Equal(t, NotIdle, c.Idle)
+	assert.Equal(t, FontSet[:], c.Memory[:len(FontSet)])
+	assert.True(t, c.Quirks.WrapSprites, "quirks are kept")
+}
+
+func TestSoftReset(t *testing.T) {
+	c := dirtyChip(t)
+	c.Reset(false)
+	assertReset(t, c)
+
+	// the ROM is loaded again as it was and the rest of memory is cleared
+	assert.Equal(t, []uint8{0x12, 0x34, 0x56, 0}, c.Memory[0x200:0x204])
+	assert.Equal(t, uint8(0), c.Memory[0x800])
+
+	// and it's kept for the next reset
+	c.Memory[0x201] = 0
+	c.Reset(false)
+	assert.Equal(t, uint8(0x34), c.Memory[0x201])
+}
+
+func TestHardReset(t *testing.T) {
+	c := dirtyChip(t)
+	c.Reset(true)
+	assertReset(t, c)
+
+	assert.Equal(t, make([]uint8, len(c.Memory)-len(FontSet)), c.Memory[len(FontSet):], "only the font is left")
+
+	// the ROM is forgotten
+	c.Reset(false)
+	assert.Equal(t, uint8(0), c.Memory[0x200])
+}
+
+func TestResetInvalidatesTheInstructionCache(t *testing.T) {
+	c := NewDefaultChip()
+	c.Initialise()
+	c.EnableInstructionCache()
+	require.NoError(t, c.LoadROM([]byte{0x60, 0x01}))
+	require.NoError(t, c.EmulateCycle())
+
+	// a different ROM at the same address
+	c.Reset(true)
+	require.NoError(t, c.LoadROM([]byte{0x61, 0x02}))
+	require.NoError(t, c.EmulateCycle())
+	assert.Equal(t, uint8(0), c.V[0])
+	assert.Equal(t, uint8(2), c.V[1])
+}
+
+func TestLoadROMTooBig(t *testing.T) {
+	c := NewDefaultChip()
+	c.Initialise()
+	require.NoError(t, c.LoadROM([]byte{0x60, 0x01}))
+
+	rom := make([]byte, MaxROMSize+1)
+	assert.True(t, errors.Is(CheckROM(rom), utils.MemoryOutOfRange))
+	assert.True(t, errors.Is(c.LoadROM(rom), utils.MemoryOutOfRange))
+	assert.NoError(t, CheckROM(rom[:MaxROMSize]))
+
+	// the ROM that was loaded is still there
+	assert.Equal(t, uint8(0x60), c.Memory[0x200])
 }
@@ -0,0 +1,107 @@
+package chip
+
+import "fmt"
+
+// Disassemble returns the mnemonic for an opcode, using the usual Cowgod style names (CLS, JP, LD Vx, NN ...).
+// Anything that isn't a known instruction is returned as a raw data word.
+func Disassemble(opcode uint16) string {
+	x := opcode & 0x0f00 >> 8
+	y := opcode & 0x00f0 >> 4
+	n := opcode & 0x000f
+	nn := opcode & 0x00ff
+	nnn := opcode & 0x0fff
+
+	switch opcode & 0xf000 {
+	case 0x0000:
+		switch opcode {
+		case 0x00e0:
+			return "CLS"
+		case 0x00ee:
+			return "RET"
+		case 0x00fe:
+			return "LOW"
+		case 0x00ff:
+			return "HIGH"
+		}
+		return fmt.Sprintf("SYS 0x%03x", nnn)
+	case 0x1000:
+		return fmt.Sprintf("JP 0x%03x", nnn)
+	case 0x2000:
+		return fmt.Sprintf("CALL 0x%03x", nnn)
+	case 0x3000:
+		return fmt.Sprintf("SE V%X, 0x%02x", x, nn)
+	case 0x4000:
+		return fmt.Sprintf("SNE V%X, 0x%02x", x, nn)
+	case 0x5000:
+		if n == 0 {
+			return fmt.Sprintf("SE V%X, V%X", x, y)
+		}
+	case 0x6000:
+		return fmt.Sprintf("LD V%X, 0x%02x", x, nn)
+	case 0x7000:
+		return fmt.Sprintf("ADD V%X, 0x%02x", x, nn)
+	case 0x8000:
+		switch n {
+		case 0x0:
+			return fmt.Sprintf("LD V%X, V%X", x, y)
+		case 0x1:
+			return fmt.Sprintf("OR V%X, V%X", x, y)
+		case 0x2:
+			return fmt.Sprintf("AND V%X, V%X", x, y)
+		case 0x3:
+			return fmt.Sprintf("XOR V%X, V%X", x, y)
+		case 0x4:
+			return fmt.Sprintf("ADD V%X, V%X", x, y)
+		case 0x5:
+			return fmt.Sprintf("SUB V%X, V%X", x, y)
+		case 0x6:
+			return fmt.Sprintf("SHR V%X", x)
+		case 0x7:
+			return fmt.Sprintf("SUBN V%X, V%X", x, y)
+		case 0xe:
+			return fmt.Sprintf("SHL V%X", x)
+		}
+	case 0x9000:
+		if n == 0 {
+			return fmt.Sprintf("SNE V%X, V%X", x, y)
+		}
+	case 0xa000:
+		return fmt.Sprintf("LD I, 0x%03x", nnn)
+	case 0xb000:
+		return fmt.Sprintf("JP V0, 0x%03x", nnn)
+	case 0xc000:
+		return fmt.Sprintf("RND V%X, 0x%02x", x, nn)
+	case 0xd000:
+		return fmt.Sprintf("DRW V%X, V%X, %d", x, y, n)
+	case 0xe000:
+		switch nn {
+		case 0x9e:
+			return fmt.Sprintf("SKP V%X", x)
+		case 0xa1:
+			return fmt.Sprintf("SKNP V%X", x)
+		}
+	case 0xf000:
+		switch nn {
+		case 0x07:
+			return fmt.Sprintf("LD V%X, DT", x)
+		case 0x0a:
+			return fmt.Sprintf("LD V%X, K", x)
+		case 0x15:
+			return fmt.Sprintf("LD DT, V%X", x)
+		case 0x18:
+			return fmt.Sprintf("LD ST, V%X", x)
+		case 0x1e:
+			return fmt.Sprintf("ADD I, V%X", x)
+		case 0x29:
+			return fmt.Sprintf("LD F, V%X", x)
+		case 0x33:
+			return fmt.Sprintf("LD B, V%X", x)
+		case 0x55:
+			return fmt.Sprintf("LD [I], V%X", x)
+		case 0x65:
+			return fmt.Sprintf("LD V%X, [I]", x)
+		}
+	}
+
+	return fmt.Sprintf("DW 0x%04x", opcode)
+}
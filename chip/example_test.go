@@ -0,0 +1,93 @@
+package chip_test
+
+import (
+	"fmt"
+
+	"github.com/cuotos/chip8/chip"
+)
+
+func Example() {
+	c := chip.New(chip.WithVariant(chip.VariantCOSMAC))
+
+	// V0 = 5, V0 += 3, then loop forever
+	if err := c.LoadROM([]byte{0x60, 0x05, 0x70, 0x03, 0x12, 0x04}); err != nil {
+		panic(err)
+	}
+	if err := c.RunFrame(); err != nil {
+		panic(err)
+	}
+
+	s := c.State()
+	fmt.Printf("V0=%d PC=%03x\n", s.V[0], s.PC)
+	// Output: V0=8 PC=204
+}
+
+func ExampleWithOpcode() {
+	// 5XY1 multiplies VX by VY
+	multiply := func(c *chip.Chip8) error {
+		x, y := c.OpCode&0x0f00>>8, c.OpCode&0x00f0>>4
+		c.V[x] *= c.V[y]
+		c.PC += 2
+		return nil
+	}
+	c := chip.New(chip.WithOpcode(0x5001, 0xf00f, multiply))
+
+	// V0 = 6, V1 = 7, V0 *= V1
+	if err := c.LoadROM([]byte{0x60, 0x06, 0x61, 0x07, 0x50, 0x11}); err != nil {
+		panic(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := c.Step(); err != nil {
+			panic(err)
+		}
+	}
+
+	fmt.Println(c.State().V[0])
+	// Output: 42
+}
+
+type printBeeper struct{}
+
+func (printBeeper) Beep(on bool) {
+	fmt.Println("beep", on)
+}
+
+func ExampleWithAudio() {
+	c := chip.New(chip.WithAudio(printBeeper{}))
+
+	// V0 = 2, sound timer = V0, then loop forever
+	if err := c.LoadROM([]byte{0x60, 0x02, 0xf0, 0x18, 0x12, 0x04}); err != nil {
+		panic(err)
+	}
+	for frame := 0; frame < 3; frame++ {
+		if err := c.RunFrame(); err != nil {
+			panic(err)
+		}
+	}
+	// Output:
+	// beep true
+	// beep false
+}
+
+type heldKeys [16]bool
+
+func (k heldKeys) Keys() [16]bool {
+	return k
+}
+
+func ExampleWithInput() {
+	var keys heldKeys
+	keys[0x5] = true
+	c := chip.New(chip.WithInput(keys))
+
+	// wait for a key and put it in V3, then loop forever
+	if err := c.LoadROM([]byte{0xf3, 0x0a, 0x12, 0x02}); err != nil {
+		panic(err)
+	}
+	if err := c.RunFrame(); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("V3=%x\n", c.State().V[3])
+	// Output: V3=5
+}
@@ -0,0 +1,58 @@
+package chip
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// how long each fuzzed ROM is allowed to run for
+const fuzzCycles = 2000
+
+// FuzzEmulateCycle runs arbitrary ROM images with a sequence of keypad states and checks the chip never panics and
+// never ends up in an impossible state without returning an error. Each byte of keys is the keypad for one cycle, the
+// low nibble is the key and bit 4 says if it is held down. The sequence repeats for as long as the ROM runs.
+func FuzzEmulateCycle(f *testing.F) {
+	roms, _ := filepath.Glob(filepath.Join("..", "roms", "*.ch8"))
+	for _, path := range roms {
+		rom, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(rom, []byte{0x00, 0x11, 0x14, 0x16, 0x1c, 0x0d})
+	}
+
+	f.Fuzz(func(t *testing.T, rom []byte, keys []byte) {
+		var seed uint8
+		c := NewChip8(nil, func() uint8 {
+			seed = seed*13 + 7
+			return seed
+		})
+		c.Initialise()
+
+		if err := c.LoadROM(rom); err != nil {
+			return
+		}
+
+		for i := 0; i < fuzzCycles; i++ {
+			if len(keys) > 0 {
+				k := keys[i%len(keys)]
+				c.Keypad = [16]uint8{}
+				if k&0x10 != 0 {
+					c.Keypad[k&0xf] = 1
+				}
+			}
+
+			if err := c.EmulateCycle(); err != nil {
+				return
+			}
+
+			if int(c.PC)+1 >= len(c.Memory) {
+				t.Fatalf("cycle %d: PC %04x is outside of memory", i, c.PC)
+			}
+			if int(c.SP) > len(c.Stack) {
+				t.Fatalf("cycle %d: SP %x is outside of the stack", i, c.SP)
+			}
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package chip
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cuotos/chip8/gfx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyPress holds a key down for a number of frames
+type keyPress struct {
+	Frame  int
+	Key    uint8
+	Frames int
+}
+
+type goldenCase struct {
+	Name    string
+	ROM     string  // path to a ROM file, relative to chip/
+	Program []uint8 // or a program to load at 0x200
+	Frames  int
+	Keys    []keyPress
+}
+
+// runFrames runs the chip for a number of frames, holding keys down as scripted and presenting the display whenever
+// it's been drawn to
+func runFrames(t *testing.T, c *Chip8, display gfx.GFX, frames int, keys []keyPress) {
+	for frame := 0; frame < frames; frame++ {
+		c.Keypad = [16]uint8{}
+		for _, k := range keys {
+			if frame >= k.Frame && frame < k.Frame+k.Frames {
+				c.Keypad[k.Key] = 1
+			}
+		}
+
+		for i := 0; i < CyclesPerFrame; i++ {
+			require.NoError(t, c.EmulateCycle(), "frame %d", frame)
+		}
+		c.TickTimers()
+
+		if c.DrawFlag {
+			display.Present(c.Display)
+			c.DrawFlag = false
+		}
+	}
+}
+
+var update = flag.Bool("update", false, "regenerate the golden files in testdata/golden")
+
+// assertGolden compares the text art of the display with testdata/golden/<name>.txt, regenerating it with -update
+func assertGolden(t *testing.T, name string, display *gfx.Headless) {
+	path := filepath.Join("testdata", "golden", name+".txt")
+	actual := display.String()
+
+	if *update {
+		require.NoError(t, ioutil.WriteFile(path, []byte(actual), 0644))
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "run with -update to create the golden file")
+
+	if !assert.Equal(t, string(expected), actual) {
+		t.Logf("got:\n%s", actual)
+	}
+}
+
+func TestGoldenFrames(t *testing.T) {
+	tcs := []goldenCase{
+		{
+			Name: "draw_font",
+			Program: []uint8{
+				0x60, 0x0a, // 200: V0 = 0xa
+				0xf0, 0x29, // 202: I = font(V0)
+				0x61, 0x02, // 204: V1 = 2
+				0x62, 0x03, // 206: V2 = 3
+				0xd1, 0x25, // 208: draw 5 rows at V1, V2
+				0x12, 0x0a, // 20a: jump 0x20a
+			},
+			Frames: 2,
+		},
+		{
+			// the second sprite overlaps the first, any pixel lit by both is turned off
+			Name: "draw_twice",
+			Program: []uint8{
+				0xa0, 0x50, // 200: I = heart
+				0xd0, 0x05, // 202: draw 5 rows at V0, V0
+				0x60, 0x02, // 204: V0 = 2
+				0xd0, 0x05, // 206: draw 5 rows at V0, V0, overlapping the first
+				0x12, 0x08, // 208: jump 0x208
+			},
+			Frames: 2,
+		},
+		{
+			Name: "clear_screen",
+			Program: []uint8{
+				0xa0, 0x50, // 200: I = heart
+				0xd0, 0x05, // 202: draw 5 rows at V0, V0
+				0x00, 0xe0, // 204: clear
+				0x60, 0x10, // 206: V0 = 16
+				0xd0, 0x05, // 208: draw 5 rows at V0, V0
+				0x12, 0x0a, // 20a: jump 0x20a
+			},
+			Frames: 2,
+		},
+		{
+			Name:   "bc_test",
+			ROM:    "../roms/bc_test.ch8",
+			Frames: 120,
+		},
+		{
+			Name:   "test_opcode",
+			ROM:    "../test_opcode.ch8",
+			Frames: 120,
+		},
+		{
+			Name:   "pong",
+			ROM:    "../roms/pong.ch8",
+			Frames: 60,
+			Keys: []keyPress{
+				{Frame: 10, Key: 0x1, Frames: 20},
+				{Frame: 30, Key: 0xc, Frames: 10},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			display := gfx.NewHeadlessGFX()
+
+			var seed uint8
+			c := NewChip8(nil, func() uint8 {
+				seed = seed*13 + 7
+				return seed
+			})
+			c.Initialise()
+
+			if tc.ROM != "" {
+				require.NoError(t, c.Load(tc.ROM))
+			} else {
+				require.NoError(t, c.LoadROM(tc.Program))
+			}
+
+			runFrames(t, c, display, tc.Frames, tc.Keys)
+
+			assertGolden(t, tc.Name, display)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package chip
+
+// IdleState says if the ROM is stuck in a loop that can't make progress on its own
+type IdleState int
+
+const (
+	NotIdle IdleState = iota
+	// IdleHalted is a loop that nothing can break out of, usually a jump to itself at the end of a ROM
+	IdleHalted
+	// IdleWaitTimer is a loop reading the delay timer, and maybe the keypad too, nothing changes until the next timer
+	// tick
+	IdleWaitTimer
+	// IdleWaitKey is a loop polling the keypad or FX0A, nothing changes until a key is pressed or released
+	IdleWaitKey
+)
+
+func (s IdleState) String() string {
+	switch s {
+	case NotIdle:
+		return "running"
+	case IdleHalted:
+		return "halted"
+	case IdleWaitTimer:
+		return "waiting for timer"
+	case IdleWaitKey:
+		return "waiting for key"
+	}
+	return "unknown"
+}
+
+// loopState is everything a loop could be waiting on to change
+type loopState struct {
+	pc     uint16
+	v      [16]uint8
+	i      uint16
+	sp     uint16
+	delay  uint8
+	keypad [16]uint8
+}
+
+// idleDetector watches for loops, a jump backwards that ends up in exactly the same state as the last time it was
+// taken, without anything in between writing to memory, drawing or using the random number generator.
+type idleDetector struct {
+	loopStart uint16
+	loopEnd   uint16
+	last      loopState
+	seen      bool
+
+	// what happened since the last backwards jump
+	progress  bool
+	readTimer bool
+	readKeys  bool
+}
+
+// detectIdle is run after every instruction with the PC it was fetched from
+func (c *Chip8) detectIdle(pc uint16) {
+	d := &c.idle
+	op := c.OpCode
+
+	// leaving the loop means it wasn't stuck after all
+	if c.Idle != NotIdle && (pc < d.loopStart || pc > d.loopEnd) {
+		c.setIdle(NotIdle)
+		d.seen = false
+	}
+
+	switch {
+	case op&0xf000 == 0xd000 && c.PC == pc:
+		// DXYN waiting for the vertical blank, see Quirks.DisplayWait
+		d.loopStart, d.loopEnd = pc, pc
+		c.setIdle(IdleWaitTimer)
+		return
+
+	case op == 0x00e0, op == 0x00fe, op == 0x00ff, op&0xf000 == 0xc000, op&0xf000 == 0xd000:
+		d.progress = true
+	case op&0xf0ff == 0xf033, op&0xf0ff == 0xf055, op&0xf0ff == 0xf015, op&0xf0ff == 0xf018:
+		d.progress = true
+	case op&0xf0ff == 0xf007:
+		d.readTimer = true
+	case op&0xf0ff == 0xe09e, op&0xf0ff == 0xe0a1:
+		d.readKeys = true
+
+	case op&0xf0ff == 0xf00a:
+		if c.PC == pc {
+			d.loopStart, d.loopEnd = pc, pc
+			c.setIdle(IdleWaitKey)
+		}
+		return
+	}
+
+	jump := op&0xf000 == 0x1000 || op&0xf000 == 0xb000
+	if !jump || c.PC > pc {
+		return
+	}
+
+	// a jump to itself can never get out, there's nothing in the loop to wait on
+	if c.PC == pc {
+		d.loopStart, d.loopEnd = pc, pc
+		c.setIdle(IdleHalted)
+		return
+	}
+
+	state := loopState{
+		pc:     c.PC,
+		v:      c.V,
+		i:      c.I,
+		sp:     c.SP,
+		delay:  c.DelayTimer,
+		keypad: c.Keypad,
+	}
+
+	if d.seen && d.loopStart == c.PC && d.loopEnd == pc && !d.progress && d.last == state {
+		// a loop reading the timer as well as the keys has to be woken by the next tick, the timer may be all it's
+		// waiting for
+		switch {
+		case d.readTimer:
+			c.setIdle(IdleWaitTimer)
+		case d.readKeys:
+			c.setIdle(IdleWaitKey)
+		default:
+			c.setIdle(IdleHalted)
+		}
+	} else {
+		c.setIdle(NotIdle)
+	}
+
+	d.loopStart, d.loopEnd = c.PC, pc
+	d.last = state
+	d.seen = true
+	d.progress, d.readTimer, d.readKeys = false, false, false
+}
+
+// setIdle updates Idle, calling OnIdle if it changed
+func (c *Chip8) setIdle(s IdleState) {
+	if c.Idle == s {
+		return
+	}
+	c.Idle = s
+	if c.OnIdle != nil {
+		c.OnIdle(s)
+	}
+}
+
+// SetKey presses or releases a key, waking the chip if it was waiting on the keypad
+func (c *Chip8) SetKey(key uint8, pressed bool) {
+	var v uint8
+	if pressed {
+		v = 1
+	}
+	if c.Keypad[key&0xf] == v {
+		return
+	}
+	c.Keypad[key&0xf] = v
+
+	if c.Idle == IdleWaitKey {
+		c.setIdle(NotIdle)
+	}
+}
@@ -0,0 +1,177 @@
+package chip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runCycles(t *testing.T, c *Chip8, n int) {
+	for i := 0; i < n; i++ {
+		require.NoError(t, c.EmulateCycle())
+	}
+}
+
+func TestIdleJumpToSelf(t *testing.T) {
+	var events []IdleState
+	c := NewDefaultChip()
+	c.OnIdle = func(s IdleState) {
+		events = append(events, s)
+	}
+	loadProgram(c, []uint8{
+		0x6a, 0x01, // 200: VA = 1
+		0x12, 0x02, // 202: jump 0x202
+	})
+
+	runCycles(t, c, 1)
+	assert.Equal(t, NotIdle, c.Idle)
+
+	runCycles(t, c, 1)
+	assert.Equal(t, IdleHalted, c.Idle)
+
+	runCycles(t, c, 10)
+	assert.Equal(t, IdleHalted, c.Idle)
+	assert.Equal(t, []IdleState{IdleHalted}, events)
+}
+
+func TestIdleWaitTimer(t *testing.T) {
+	c := NewDefaultChip()
+	loadProgram(c, []uint8{
+		0x63, 0x03, // 200: V3 = 3
+		0xf3, 0x15, // 202: DT = V3
+		0xf1, 0x07, // 204: V1 = DT
+		0x31, 0x00, // 206: skip if V1 == 0
+		0x12, 0x04, // 208: jump 0x204
+		0x6a, 0x01, // 20a: VA = 1
+		0x12, 0x0c, // 20c: jump 0x20c
+	})
+
+	// set up and go around the loop twice
+	runCycles(t, c, 8)
+	assert.Equal(t, IdleWaitTimer, c.Idle)
+
+	// it stays idle until the timer moves
+	runCycles(t, c, 6)
+	assert.Equal(t, IdleWaitTimer, c.Idle)
+
+	c.TickTimers()
+	assert.Equal(t, NotIdle, c.Idle)
+
+	// the first time round V1 has changed so it's not stuck, the second time it is
+	runCycles(t, c, 3)
+	assert.Equal(t, NotIdle, c.Idle)
+	runCycles(t, c, 3)
+	assert.Equal(t, IdleWaitTimer, c.Idle)
+
+	// run the timer out and it falls out of the loop, then halts
+	c.TickTimers()
+	c.TickTimers()
+	runCycles(t, c, 5)
+	assert.Equal(t, uint16(0x20c), c.PC)
+	assert.Equal(t, uint8(1), c.V[0xa])
+	assert.Equal(t, IdleHalted, c.Idle)
+}
+
+func TestIdleWaitTimerAndKey(t *testing.T) {
+	c := New(WithCyclesPerFrame(50))
+	require.NoError(t, c.LoadROM([]uint8{
+		0x63, 0x05, // 200: V3 = 5
+		0xf3, 0x15, // 202: DT = V3
+		0xf1, 0x07, // 204: V1 = DT
+		0x31, 0x00, // 206: skip if V1 == 0
+		0x12, 0x0c, // 208: jump 0x20c
+		0x12, 0x10, // 20a: jump 0x210
+		0xe5, 0x9e, // 20c: skip if key V5 is down
+		0x12, 0x04, // 20e: jump 0x204
+		0x12, 0x10, // 210: jump 0x210
+	}))
+
+	// it's waiting on the timer as much as the keypad, so running the timer out gets it out of the loop
+	var states []IdleState
+	c.OnIdle = func(s IdleState) {
+		states = append(states, s)
+	}
+	require.NoError(t, c.RunFrame())
+	assert.Less(t, c.Cycles, uint64(50), "the frame stops early once it's idle")
+	assert.Equal(t, []IdleState{IdleWaitTimer, NotIdle}, states, "and the tick at the end of the frame wakes it")
+	for i := 0; i < 10 && c.PC != 0x210; i++ {
+		require.NoError(t, c.RunFrame())
+	}
+	assert.Equal(t, uint16(0x210), c.PC)
+	assert.Equal(t, IdleHalted, c.Idle)
+}
+
+func TestIdleWaitKey(t *testing.T) {
+	c := NewDefaultChip()
+	loadProgram(c, []uint8{
+		0x60, 0x05, // 200: V0 = 5
+		0xe0, 0x9e, // 202: skip if key V0 is down
+		0x12, 0x02, // 204: jump 0x202
+		0x6a, 0x01, // 206: VA = 1
+		0x12, 0x08, // 208: jump 0x208
+	})
+
+	runCycles(t, c, 5)
+	assert.Equal(t, IdleWaitKey, c.Idle)
+
+	// a key that isn't being polled still wakes it up, it's up to the ROM to decide if it cares
+	c.SetKey(0x3, true)
+	assert.Equal(t, NotIdle, c.Idle)
+	runCycles(t, c, 4)
+	assert.Equal(t, IdleWaitKey, c.Idle)
+
+	c.SetKey(0x5, true)
+	assert.Equal(t, NotIdle, c.Idle)
+	runCycles(t, c, 3)
+	assert.Equal(t, uint16(0x208), c.PC)
+	assert.Equal(t, uint8(1), c.V[0xa])
+	assert.Equal(t, IdleHalted, c.Idle)
+}
+
+func TestIdleFX0A(t *testing.T) {
+	c := NewDefaultChip()
+	loadProgram(c, []uint8{
+		0xf3, 0x0a, // 200: V3 = wait for key
+		0x6a, 0x01, // 202: VA = 1
+	})
+
+	runCycles(t, c, 1)
+	assert.Equal(t, IdleWaitKey, c.Idle)
+
+	c.SetKey(0xc, true)
+	runCycles(t, c, 2)
+	assert.Equal(t, NotIdle, c.Idle)
+	assert.Equal(t, uint8(0xc), c.V[3])
+	assert.Equal(t, uint8(1), c.V[0xa])
+}
+
+func TestIdleNotWhileMakingProgress(t *testing.T) {
+	tcs := []struct {
+		Name    string
+		Program []uint8
+	}{
+		{"counting", []uint8{
+			0x7a, 0x01, // 200: VA += 1
+			0x12, 0x00, // 202: jump 0x200
+		}},
+		{"drawing", []uint8{
+			0xd0, 0x05, // 200: draw
+			0x12, 0x00, // 202: jump 0x200
+		}},
+		{"random", []uint8{
+			0xc0, 0x01, // 200: V0 = rand & 1
+			0x12, 0x00, // 202: jump 0x200
+		}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := NewChip8(nil, func() uint8 { return 0 })
+			loadProgram(c, tc.Program)
+
+			runCycles(t, c, 100)
+			assert.Equal(t, NotIdle, c.Idle)
+		})
+	}
+}
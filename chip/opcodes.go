@@ -2,30 +2,53 @@ package chip
 
 import (
 	"fmt"
+
+	"github.com/cuotos/chip8/gfx"
+	"github.com/cuotos/chip8/utils"
 )
 
 type opcodes map[uint16]func(*Chip8)
 
 var defaultOpcodes = opcodes{
 	0x00e0: func(c *Chip8) {
-		c.GFX.Clear()
+		c.Display.Clear()
 		c.DrawFlag = true
 		c.PC += 2
 	},
 
 	//00EE	Flow	return;	Returns from a subroutine.
 	0x00ee: func(c *Chip8) {
+		if c.SP == 0 {
+			c.fault(utils.StackUnderflow, "return with an empty stack")
+			return
+		}
 		c.SP -= 1
 		c.PC = c.Stack[c.SP]
 		c.PC += 2
 	},
 
+	//00FE	Display	lores	SUPER-CHIP, switches to the 64x32 display, clearing it
+	0x00fe: func(c *Chip8) {
+		c.setResolution(gfx.Width, gfx.Height)
+		c.PC += 2
+	},
+
+	//00FF	Display	hires	SUPER-CHIP, switches to the 128x64 display, clearing it
+	0x00ff: func(c *Chip8) {
+		c.setResolution(gfx.HiResWidth, gfx.HiResHeight)
+		c.PC += 2
+	},
+
 	0x1000: func(c *Chip8) {
 		c.PC = c.OpCode & 0x0FFF
 	},
 
 	//2NNN - Calls subroutine at NNN
 	0x2000: func(c *Chip8) {
+		if int(c.SP) >= len(c.Stack) {
+			c.fault(utils.StackOverflow, "call with %d addresses on the stack", c.SP)
+			return
+		}
 		c.Stack[c.SP] = c.PC
 		c.SP++
 
@@ -98,38 +121,44 @@ var defaultOpcodes = opcodes{
 			c.V[regX] = c.V[regX] & c.V[regY]
 		case 0x3:
 			c.V[regX] = c.V[regX] ^ c.V[regY]
-		// regX + regY, set VF if carry
+		// regX + regY, set VF if carry. The flags are all written after the result, so VF ends up holding the flag
+		// even when it's also VX.
 		case 0x4:
+			var flag uint8
 			if c.V[regX] > (255 - c.V[regY]) {
-				c.V[VF] = 1
-			} else {
-				c.V[VF] = 0
+				flag = 1
 			}
 			c.V[regX] += c.V[regY]
-		// regX - regY, set VF if borrow
+			c.V[VF] = flag
+		// regX - regY, VF is 0 if there's a borrow, 1 if there isn't
 		case 0x5:
-			if c.V[regX] > c.V[regY] {
-				c.V[VF] = 1
-			} else {
-				c.V[VF] = 0
+			var flag uint8
+			if c.V[regX] >= c.V[regY] {
+				flag = 1
 			}
 			c.V[regX] = c.V[regX] - c.V[regY]
+			c.V[VF] = flag
 		// 8XY6[a]	BitOp	Vx>>=1	Stores the least significant bit of VX in VF and then shifts VX to the right by 1.[b]
 		case 0x6:
-			c.V[VF] = c.V[regX] & 0x1
+			flag := c.V[regX] & 0x1
 			c.V[regX] = c.V[regX] >> 1
+			c.V[VF] = flag
 		// 8XY7[a]	Math	Vx=Vy-Vx	Sets VX to VY minus VX. VF is set to 0 when there's a borrow, and 1 when there isn't.
 		case 0x7:
-			if c.V[regY] > c.V[regX] {
-				c.V[VF] = 1
-			} else {
-				c.V[VF] = 0
+			var flag uint8
+			if c.V[regY] >= c.V[regX] {
+				flag = 1
 			}
 			c.V[regX] = c.V[regY] - c.V[regX]
+			c.V[VF] = flag
 		// 8XYE[a]	BitOp	Vx<<=1	Stores the most significant bit of VX in VF and then shifts VX to the left by 1.[b]
 		case 0xe:
-			c.V[VF] = (c.V[regX] & 0x80) >> 7
+			flag := (c.V[regX] & 0x80) >> 7
 			c.V[regX] = c.V[regX] << 1
+			c.V[VF] = flag
+		default:
+			c.fault(utils.UnkownOpcode, "no such maths instruction")
+			return
 		}
 
 		c.PC += 2
@@ -167,31 +196,57 @@ var defaultOpcodes = opcodes{
 
 	// DXYN - draw at points X, Y and sprite of N rows high
 	0xd000: func(c *Chip8) {
+		// without advancing the PC, so it's run again until the vertical blank
+		if c.Quirks.DisplayWait {
+			if !c.vblank {
+				return
+			}
+			c.vblank = false
+		}
+
 		x := c.V[c.OpCode&0x0f00>>8]
 		y := c.V[c.OpCode&0x00f0>>4]
 		h := c.OpCode & 0x000f
 
+		if int(c.I)+int(h) > len(c.Memory) {
+			c.fault(utils.MemoryOutOfRange, "sprite of %d rows at I:%04x", h, c.I)
+			return
+		}
+
 		// set collision reg to 0
 		c.V[VF] = 0
 
+		// the sprite starts anywhere on the display, it's only what's drawn past the edges that's clipped or wrapped
+		d := c.Display
+		x0 := int(x) % d.Width
+		y0 := int(y) % d.Height
+
 		for yLine := 0; yLine < int(h); yLine++ {
-			pixel := c.Memory[int(c.I)+yLine]
+			row := y0 + yLine
+			if row >= d.Height {
+				if !c.Quirks.WrapSprites {
+					break
+				}
+				row -= d.Height
+			}
+			pixels := d.Pixels[row*d.Width : (row+1)*d.Width]
+			sprite := c.Memory[int(c.I)+yLine]
 
 			for xLine := 0; xLine < 8; xLine++ {
-				// TODO: Need to fully understand this line
-				//  0x80 (128 aka 1000 0000) and shift the 1 across 8 times
-				if (pixel & (0x80 >> xLine)) != 0 {
-
-					yPlusYLine := uint16(y) + uint16(yLine)
-					yPlusYLine64 := yPlusYLine * 64
-					xPlusXLine := uint16(x) + uint16(xLine)
-					cell := xPlusXLine + yPlusYLine64
+				col := x0 + xLine
+				if col >= d.Width {
+					if !c.Quirks.WrapSprites {
+						break
+					}
+					col -= d.Width
+				}
 
-					p := c.GFX.GetPixel(cell)
-					if p == 1 {
+				//  0x80 (1000 0000) shifted right picks out each bit of the row, left to right
+				if (sprite & (0x80 >> xLine)) != 0 {
+					if pixels[col] == 1 {
 						c.V[VF] = 1
 					}
-					c.GFX.SetPixel(cell, p ^ 1)
+					pixels[col] ^= 1
 				}
 			}
 		}
@@ -201,7 +256,9 @@ var defaultOpcodes = opcodes{
 	},
 
 	0xe09e: func(c *Chip8) {
-		if c.Keypad[c.OpCode&0x0f00>>8] != 0x0{
+		key := c.V[c.OpCode&0x0f00>>8] & 0xf
+		c.KeysPolled |= 1 << key
+		if c.Keypad[key] != 0x0{
 			c.PC += 2
 		}
 
@@ -209,7 +266,9 @@ var defaultOpcodes = opcodes{
 	},
 
 	0xe0a1: func(c *Chip8) {
-		if c.Keypad[c.OpCode&0x0f00>>8] == 0x0{
+		key := c.V[c.OpCode&0x0f00>>8] & 0xf
+		c.KeysPolled |= 1 << key
+		if c.Keypad[key] == 0x0{
 			c.PC += 2
 		}
 		c.PC += 2
@@ -221,11 +280,24 @@ var defaultOpcodes = opcodes{
 		case 0x07:
 			c.V[c.OpCode&0x0f00>>8] = c.DelayTimer
 
+		// wait for a key press by running this instruction again until one is down
 		case 0x0a:
-			panic("0x0a not implemented")
+			// any key will do
+			c.KeysPolled = 0xffff
+			for k, pressed := range c.Keypad {
+				if pressed != 0 {
+					c.V[c.OpCode&0x0f00>>8] = uint8(k)
+					c.PC += 2
+					return
+				}
+			}
+			return
 
 		case 0x15:
-			c.DelayTimer = uint8(c.OpCode & 0x0f00 >> 8)
+			c.DelayTimer = c.V[c.OpCode&0x0f00>>8]
+
+		case 0x18:
+			c.SoundTimer = c.V[c.OpCode&0x0f00>>8]
 
 		case 0x1e:
 			reg := c.OpCode & 0x0f00 >> 8
@@ -237,22 +309,38 @@ var defaultOpcodes = opcodes{
 			c.I = uint16(char) * 5
 
 		case 0x33:
+			if int(c.I)+3 > len(c.Memory) {
+				c.fault(utils.MemoryOutOfRange, "BCD to I:%04x", c.I)
+				return
+			}
 			reg := c.V[c.OpCode&0xf00>>8]
-			c.Memory[c.I] = reg / 100
-			c.Memory[c.I+1] = (reg / 10) % 10
-			c.Memory[c.I+2] = (reg % 100) % 10
+			c.WriteMemory(c.I, reg/100)
+			c.WriteMemory(c.I+1, (reg/10)%10)
+			c.WriteMemory(c.I+2, (reg%100)%10)
 
 		case 0x55:
 			numberOfRegs := c.OpCode & 0x0f00 >> 8
+			if int(c.I)+int(numberOfRegs) >= len(c.Memory) {
+				c.fault(utils.MemoryOutOfRange, "storing V0-V%X at I:%04x", numberOfRegs, c.I)
+				return
+			}
 			for i := 0; uint16(i) <= numberOfRegs; i++ {
-				c.Memory[c.I+uint16(i)] = c.V[i]
+				c.WriteMemory(c.I+uint16(i), c.V[i])
 			}
 
 		case 0x65:
 			maxReg := c.OpCode & 0xf00 >> 8
+			if int(c.I)+int(maxReg) >= len(c.Memory) {
+				c.fault(utils.MemoryOutOfRange, "loading V0-V%X from I:%04x", maxReg, c.I)
+				return
+			}
 			for i := 0; i <= int(maxReg); i++ {
 				c.V[i] = c.Memory[c.I+uint16(i)]
 			}
+
+		default:
+			c.fault(utils.UnkownOpcode, "no such FX instruction")
+			return
 		}
 
 		c.PC += 2
@@ -286,20 +374,29 @@ func (ocs *opcodes) LookupOpcode(opcode uint16) (func(c *Chip8), error) {
 	var ok bool
 	oc, ok = (*ocs)[opcodeRef]
 	if !ok {
-		return nil, fmt.Errorf("unable to lookup opcode: %04x", opcode)
+		return nil, fmt.Errorf("unable to lookup opcode: %04x: %w", opcode, utils.UnkownOpcode)
 	}
 
 	return oc, nil
 }
 
+// setResolution changes the size of the display if it isn't already that size
+func (c *Chip8) setResolution(width, height int) {
+	if c.Display.Width == width && c.Display.Height == height {
+		return
+	}
+	c.Display.Resize(width, height)
+	c.DrawFlag = true
+}
+
 func (c *Chip8) HandleOpcode() error {
 
-	f, err := c.LookupOpcode(c.OpCode)
+	f, err := c.lookup(c.OpCode)
 	if err != nil {
 		return err
 	}
 
 	f(c)
 
-	return nil
+	return c.checkFault()
 }
@@ -1,9 +1,11 @@
 package chip
 
 import (
+	"errors"
 	"fmt"
-	"github.com/cuotos/chip8/gfx"
+	"github.com/cuotos/chip8/utils"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -94,31 +96,51 @@ func TestOpcode0NNN(t *testing.T) {
 	t.Skip()
 }
 
-type mockClearingGFX struct{
-	ClearCalled bool
-}
-
-func (g *mockClearingGFX) Initialise() (func(), error) {return nil, nil}
-func (g *mockClearingGFX) SetPixel(pixel, value uint16) {}
-func (g *mockClearingGFX) GetPixel(pixel uint16) uint16 {return 0}
-func (g *mockClearingGFX) Draw(){}
-func (g *mockClearingGFX) Clear(){
-	g.ClearCalled = true
-}
-
 //00E0	Display	disp_clear()	Clears the screen.
 func TestOpcode00E0(t *testing.T) {
 	c := NewDefaultChip()
-	c.GFX = &mockClearingGFX{
-		false,
-	}
+	c.Display.Set(0, 0, true)
+	c.Display.Set(63, 31, true)
 
 	c.OpCode = 0x00e0
 
 	err := c.HandleOpcode()
 	if assert.NoError(t, err) {
-		assert.True(t, c.GFX.(*mockClearingGFX).ClearCalled)
+		assert.NotContains(t, c.Display.String(), "#")
+		assert.Equal(t, true, c.DrawFlag)
+	}
+}
+
+//00FE and 00FF	Display	SUPER-CHIP low and high resolution
+func TestOpcode00FE00FF(t *testing.T) {
+	c := NewDefaultChip()
+	c.Display.Set(0, 0, true)
+
+	c.OpCode = 0x00ff
+	err := c.HandleOpcode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 128, c.Display.Width)
+		assert.Equal(t, 64, c.Display.Height)
+		assert.NotContains(t, c.Display.String(), "#")
 		assert.Equal(t, true, c.DrawFlag)
+		assert.Equal(t, uint16(2), c.PC)
+	}
+
+	// sprites can be drawn anywhere on the bigger display
+	c.Display.Set(127, 63, true)
+	c.DrawFlag = false
+	err = c.HandleOpcode()
+	if assert.NoError(t, err) {
+		assert.True(t, c.Display.Get(127, 63), "already high resolution, nothing changes")
+		assert.Equal(t, false, c.DrawFlag)
+	}
+
+	c.OpCode = 0x00fe
+	err = c.HandleOpcode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 64, c.Display.Width)
+		assert.Equal(t, 32, c.Display.Height)
+		assert.NotContains(t, c.Display.String(), "#")
 	}
 }
 
@@ -328,6 +350,7 @@ func TestOpcode8XYNCarries(t *testing.T) {
 		//8XY5	Math	Vx -= Vy	VY is subtracted from VX. VF is set to 0 when there's a borrow, and 1 when there isn't.
 		{0x5, 0xff, 0xab, 0x54, 1},
 		{0x5, 0xab, 0xff, 0xac, 0},
+		{0x5, 0xab, 0xab, 0x00, 1}, // equal is no borrow
 
 		// 8XY6[a]	BitOp	Vx>>=1	Stores the least significant bit of VX in VF and then shifts VX to the right by 1.[b]
 		{0x6, 0x1, 0x0, 0x0, 0x1},
@@ -338,6 +361,7 @@ func TestOpcode8XYNCarries(t *testing.T) {
 		//8XY7[a]	Math	Vx=Vy-Vx	Sets VX to VY minus VX. VF is set to 0 when there's a borrow, and 1 when there isn't.
 		{0x7, 0x1, 0xff, 0xfe, 1},
 		{0x7, 0x3, 0x01, 0xfe, 0},
+		{0x7, 0xab, 0xab, 0x00, 1}, // equal is no borrow
 
 		//8XYE[a]	BitOp	Vx<<=1	Stores the most significant bit of VX in VF and then shifts VX to the left by 1.[b]
 		{0xe, 0xff, 0x0, 0xfe, 1},
@@ -367,6 +391,37 @@ func TestOpcode8XYNCarries(t *testing.T) {
 	}
 }
 
+// with VF as VX the flag is written after the result, so it's the flag that's left
+func TestOpcode8XYNFlagInVF(t *testing.T) {
+	tcs := []struct {
+		InputOpcode uint16
+		InputVF     uint8
+		InputRegY   uint8
+		ExpectedVF  uint8
+	}{
+		{0x4, 0xff, 0x01, 1},
+		{0x5, 0x07, 0x03, 1},
+		{0x6, 0x03, 0x00, 1},
+		{0x7, 0x02, 0x03, 1},
+		{0xe, 0x81, 0x00, 1},
+	}
+
+	for _, tc := range tcs {
+		t.Run(fmt.Sprintf("0x%x", (tc.InputOpcode|0x8000)), func(t *testing.T) {
+			c := NewDefaultChip()
+			c.OpCode = 0x8fb0 + tc.InputOpcode
+
+			c.V[VF] = tc.InputVF
+			c.V[0xb] = tc.InputRegY
+
+			err := c.HandleOpcode()
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.ExpectedVF, c.V[VF])
+			}
+		})
+	}
+}
+
 //9XY0	Cond	if(Vx!=Vy)	Skips the next instruction if VX doesn't equal VY. (Usually the next instruction is a jump to skip a code block)
 func TestOpcode9XY0(t *testing.T) {
 	tcs := []struct {
@@ -470,10 +525,7 @@ func TestOpcodeCXNN(t *testing.T) {
 //DXYN Draws a sprite at coordinate (VX, VY) that has a width of 8 pixels and a height of N pixels.
 /// Each row of 8 pixels is read as bit-coded starting from memory location I
 func TestOpcodeDXYN(t *testing.T) {
-
-	gfx := gfx.NewTerminalGFX()
 	c := NewDefaultChip()
-	c.GFX = gfx
 
 	// Set I and the next 2 locations, these will be the pixels (8bit row)
 	c.Memory[c.I] = 0x3c
@@ -490,9 +542,152 @@ func TestOpcodeDXYN(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	// TODO: add assertion but i couldnt be bothered to list the entire gfx buffer to compare
+	lines := strings.Split(c.Display.String(), "\n")
+	assert.Equal(t, strings.Repeat(".", 64), lines[2])
+	assert.Equal(t, strings.Repeat(".", 17)+"####"+strings.Repeat(".", 43), lines[3])
+	assert.Equal(t, strings.Repeat(".", 15)+"##....##"+strings.Repeat(".", 41), lines[4])
+	assert.Equal(t, strings.Repeat(".", 15)+"########"+strings.Repeat(".", 41), lines[5])
+	assert.Equal(t, strings.Repeat(".", 64), lines[6])
+	assert.Equal(t, uint8(0), c.V[VF])
 
-	assert.Equal(t, uint16(0x2), c.PC)
+	// drawing it again turns it all off, with a collision
+	err = c.HandleOpcode()
+	assert.NoError(t, err)
+	assert.NotContains(t, c.Display.String(), "#")
+	assert.Equal(t, uint8(1), c.V[VF])
+
+	assert.Equal(t, uint16(0x4), c.PC)
+}
+
+// litPixels lists the x, y of every lit pixel, top to bottom and left to right
+func litPixels(c *Chip8) [][2]int {
+	var lit [][2]int
+	for y := 0; y < c.Display.Height; y++ {
+		for x := 0; x < c.Display.Width; x++ {
+			if c.Display.Get(x, y) {
+				lit = append(lit, [2]int{x, y})
+			}
+		}
+	}
+	return lit
+}
+
+func TestOpcodeDXYNEdges(t *testing.T) {
+	// the sprite is 3 pixels wide and 2 tall
+	//   ###
+	//   #.#
+	tcs := []struct {
+		Name string
+		X, Y uint8
+		Clip [][2]int
+		Wrap [][2]int
+	}{
+		{"top left", 0, 0,
+			[][2]int{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {2, 1}},
+			[][2]int{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {2, 1}}},
+		{"top", 30, 0,
+			[][2]int{{30, 0}, {31, 0}, {32, 0}, {30, 1}, {32, 1}},
+			[][2]int{{30, 0}, {31, 0}, {32, 0}, {30, 1}, {32, 1}}},
+		{"top right", 62, 0,
+			[][2]int{{62, 0}, {63, 0}, {62, 1}},
+			[][2]int{{0, 0}, {62, 0}, {63, 0}, {0, 1}, {62, 1}}},
+		{"left", 0, 10,
+			[][2]int{{0, 10}, {1, 10}, {2, 10}, {0, 11}, {2, 11}},
+			[][2]int{{0, 10}, {1, 10}, {2, 10}, {0, 11}, {2, 11}}},
+		{"right", 63, 10,
+			[][2]int{{63, 10}, {63, 11}},
+			[][2]int{{0, 10}, {1, 10}, {63, 10}, {1, 11}, {63, 11}}},
+		{"bottom left", 0, 31,
+			[][2]int{{0, 31}, {1, 31}, {2, 31}},
+			[][2]int{{0, 0}, {2, 0}, {0, 31}, {1, 31}, {2, 31}}},
+		{"bottom", 30, 31,
+			[][2]int{{30, 31}, {31, 31}, {32, 31}},
+			[][2]int{{30, 0}, {32, 0}, {30, 31}, {31, 31}, {32, 31}}},
+		{"bottom right", 62, 31,
+			[][2]int{{62, 31}, {63, 31}},
+			[][2]int{{0, 0}, {62, 0}, {0, 31}, {62, 31}, {63, 31}}},
+		{"start past the bottom right", 64 + 5, 32 + 2,
+			[][2]int{{5, 2}, {6, 2}, {7, 2}, {5, 3}, {7, 3}},
+			[][2]int{{5, 2}, {6, 2}, {7, 2}, {5, 3}, {7, 3}}},
+		{"start wraps into the bottom right corner", 255, 255,
+			[][2]int{{63, 31}},
+			[][2]int{{1, 0}, {63, 0}, {0, 31}, {1, 31}, {63, 31}}},
+	}
+
+	for _, tc := range tcs {
+		for _, wrap := range []bool{false, true} {
+			name := tc.Name + " clipped"
+			expected := tc.Clip
+			if wrap {
+				name = tc.Name + " wrapped"
+				expected = tc.Wrap
+			}
+
+			t.Run(name, func(t *testing.T) {
+				c := NewDefaultChip()
+				c.Quirks.WrapSprites = wrap
+				c.I = 0x300
+				c.Memory[0x300] = 0xe0
+				c.Memory[0x301] = 0xa0
+				c.V[0] = tc.X
+				c.V[1] = tc.Y
+
+				c.OpCode = 0xd012
+				err := c.HandleOpcode()
+				if assert.NoError(t, err) {
+					assert.Equal(t, expected, litPixels(c))
+					assert.Equal(t, uint8(0), c.V[VF])
+				}
+			})
+		}
+	}
+}
+
+func TestOpcodeDXYNDisplayWait(t *testing.T) {
+	c := NewDefaultChip()
+	c.Quirks.DisplayWait = true
+	loadProgram(c, []uint8{
+		0xd0, 0x11, // 200: draw
+		0xd0, 0x11, // 202: draw again
+	})
+
+	// nothing is drawn until the vertical blank
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, c.EmulateCycle())
+	}
+	assert.Equal(t, uint16(0x200), c.PC)
+	assert.Equal(t, IdleWaitTimer, c.Idle)
+
+	c.TickTimers()
+	assert.Equal(t, NotIdle, c.Idle)
+	assert.NoError(t, c.EmulateCycle())
+	assert.Equal(t, uint16(0x202), c.PC)
+
+	// then only one sprite a frame
+	assert.NoError(t, c.EmulateCycle())
+	assert.Equal(t, uint16(0x202), c.PC)
+
+	c.TickTimers()
+	assert.NoError(t, c.EmulateCycle())
+	assert.Equal(t, uint16(0x204), c.PC)
+}
+
+func TestOpcodeDXYNCollisionAfterWrapping(t *testing.T) {
+	c := NewDefaultChip()
+	c.Quirks.WrapSprites = true
+	c.Display.Set(0, 0, true)
+	c.I = 0x300
+	c.Memory[0x300] = 0xc0
+	c.Memory[0x301] = 0xc0
+	c.V[0] = 63
+	c.V[1] = 31
+
+	c.OpCode = 0xd012
+	err := c.HandleOpcode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, [][2]int{{63, 0}, {0, 31}, {63, 31}}, litPixels(c))
+		assert.Equal(t, uint8(1), c.V[VF])
+	}
 }
 
 //EX9E	KeyOp	if(key()==Vx)	Skips the next instruction if the key stored in VX is pressed. (Usually the next instruction is a jump to skip a code block)
@@ -510,13 +705,16 @@ func TestOpcodeEX9E(t *testing.T) {
 
 	for _, tc := range tcs {
 		c := NewDefaultChip()
-		c.OpCode = 0xe09e | uint16(tc.InputKey) << 8
+		// the key is in V3, not key 3
+		c.OpCode = 0xe09e | 0x3 << 8
+		c.V[0x3] = tc.InputKey
 
 		c.Keypad[tc.InputKey] = tc.Pressed
 
 		err := c.HandleOpcode()
 		if assert.NoError(t, err) {
 			assert.Equal(t, tc.ExpectPC, c.PC)
+			assert.Equal(t, uint16(1) << tc.InputKey, c.KeysPolled)
 		}
 	}
 }
@@ -537,13 +735,16 @@ func TestOpcodeEXA1(t *testing.T) {
 
 	for _, tc := range tcs {
 		c := NewDefaultChip()
-		c.OpCode = 0xe0a1 | uint16(tc.InputKey) << 8
+		// the key is in V3, not key 3
+		c.OpCode = 0xe0a1 | 0x3 << 8
+		c.V[0x3] = tc.InputKey
 
 		c.Keypad[tc.InputKey] = tc.Pressed
 
 		err := c.HandleOpcode()
 		if assert.NoError(t, err) {
 			assert.Equal(t, tc.ExpectPC, c.PC)
+			assert.Equal(t, uint16(1) << tc.InputKey, c.KeysPolled)
 		}
 	}
 }
@@ -566,12 +767,28 @@ func TestOpcodeFX07(t *testing.T) {
 
 //FX0A	KeyOp	Vx = get_key()	A key press is awaited, and then stored in VX. (Blocking Operation. All instruction halted until next key event)
 func TestOpcodeFX0A(t *testing.T) {
-	TODO(t)
+	c := NewDefaultChip()
+	c.OpCode = 0xf30a
+
+	// nothing pressed, the instruction repeats
+	err := c.HandleOpcode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint16(0x0), c.PC)
+		assert.Equal(t, uint16(0xffff), c.KeysPolled, "waiting for any key")
+	}
+
+	c.Keypad[0xb] = 1
+	err = c.HandleOpcode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint16(0x2), c.PC)
+		assert.Equal(t, uint8(0xb), c.V[3])
+	}
 }
 
 //FX15	Timer	delay_timer(Vx)	Sets the delay timer to VX.
 func TestOpcodeFX15(t *testing.T) {
 	c := NewDefaultChip()
+	c.V[0xa] = 0x42
 
 	c.OpCode = 0xfa15
 
@@ -580,7 +797,7 @@ func TestOpcodeFX15(t *testing.T) {
 	if assert.NoError(t, err) {
 		assert.Equal(t, 2, int(c.PC))
 
-		assert.Equal(t, uint8(0xa), c.DelayTimer)
+		assert.Equal(t, uint8(0x42), c.DelayTimer)
 	}
 }
 
@@ -588,12 +805,14 @@ func TestOpcodeFX15(t *testing.T) {
 func TestOpcodeFX18(t *testing.T) {
 	c := NewDefaultChip()
 	c.V[0xa] = 0x99
-	c.OpCode = 0xfa10
+	c.OpCode = 0xfa18
 
 	err := c.HandleOpcode()
 
 	if assert.NoError(t, err){
 		assert.Equal(t, uint16(0x2), c.PC)
+
+		assert.Equal(t, uint8(0x99), c.SoundTimer)
 	}
 }
 
@@ -732,6 +951,54 @@ func TestOpcodeFX65(t *testing.T) {
 	}
 }
 
+// Instructions that would take the chip outside of its memory or stack, or that don't exist, return an error rather
+// than panicking
+func TestOpcodeFaults(t *testing.T) {
+	tcs := []struct {
+		Name     string
+		OpCode   uint16
+		Setup    func(c *Chip8)
+		Expected error
+	}{
+		{"00EE empty stack", 0x00ee, func(c *Chip8) {}, utils.StackUnderflow},
+		{"2NNN full stack", 0x2300, func(c *Chip8) { c.SP = 16 }, utils.StackOverflow},
+		{"1NNN last byte", 0x1fff, func(c *Chip8) {}, utils.MemoryOutOfRange},
+		{"3XNN skip off the end", 0x3000, func(c *Chip8) { c.PC = 0xffc }, utils.MemoryOutOfRange},
+		{"BNNN past end", 0xbfff, func(c *Chip8) { c.V[0] = 0x10 }, utils.MemoryOutOfRange},
+		{"DXYN sprite past end", 0xd01f, func(c *Chip8) { c.I = 0xff8 }, utils.MemoryOutOfRange},
+		{"FX33 past end", 0xf033, func(c *Chip8) { c.I = 0xffe }, utils.MemoryOutOfRange},
+		{"FX55 past end", 0xf355, func(c *Chip8) { c.I = 0xffd }, utils.MemoryOutOfRange},
+		{"FX65 past end", 0xf365, func(c *Chip8) { c.I = 0xffd }, utils.MemoryOutOfRange},
+		{"FX65 I wraps", 0xff65, func(c *Chip8) { c.I = 0xfff5 }, utils.MemoryOutOfRange},
+		{"8XYN unknown", 0x8128, func(c *Chip8) {}, utils.UnkownOpcode},
+		{"FXNN unknown", 0xf090, func(c *Chip8) {}, utils.UnkownOpcode},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := NewDefaultChip()
+			tc.Setup(c)
+			c.OpCode = tc.OpCode
+
+			err := c.HandleOpcode()
+			assert.True(t, errors.Is(err, tc.Expected), "expected %s, got %v", tc.Expected, err)
+
+			// the fault doesn't stick around for the next instruction
+			c.OpCode = 0x6000
+			c.PC = 0x200
+			assert.NoError(t, c.HandleOpcode())
+		})
+	}
+}
+
+func TestUnknownOpcodeIsWrapped(t *testing.T) {
+	c := NewDefaultChip()
+	c.OpCode = 0x0123
+
+	err := c.HandleOpcode()
+	assert.True(t, errors.Is(err, utils.UnkownOpcode))
+}
+
 func TODO(t *testing.T) {
 	t.Skip("not yet implemented")
 }
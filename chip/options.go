@@ -0,0 +1,146 @@
+package chip
+
+import (
+	"github.com/cuotos/chip8/gfx"
+)
+
+// Option configures a chip made with New
+type Option func(*Chip8)
+
+// Variant is a preset of Quirks for one of the well known interpreters
+type Variant int
+
+const (
+	// VariantCHIP8 is the zero Quirks, how this emulator has always behaved
+	VariantCHIP8 Variant = iota
+	// VariantCOSMAC is the original COSMAC VIP interpreter, drawing waits for the vertical blank
+	VariantCOSMAC
+	// VariantOcto matches the Octo IDE, sprites wrap around the edges of the display
+	VariantOcto
+)
+
+// Quirks are the behaviours of the variant
+func (v Variant) Quirks() Quirks {
+	switch v {
+	case VariantCOSMAC:
+		return Quirks{DisplayWait: true}
+	case VariantOcto:
+		return Quirks{WrapSprites: true}
+	default:
+		return Quirks{}
+	}
+}
+
+// Audio plays the buzzer, Beep is called whenever the sound timer starts or stops
+type Audio interface {
+	Beep(on bool)
+}
+
+// Input is polled for the state of the keypad at the start of each RunFrame
+type Input interface {
+	Keys() [16]bool
+}
+
+// OpcodeHandler runs an instruction and is responsible for moving the PC on. An error stops the instruction and is
+// returned from Step.
+type OpcodeHandler func(c *Chip8) error
+
+// customOpcode is an OpcodeHandler for every opcode where opcode&mask == pattern
+type customOpcode struct {
+	pattern, mask uint16
+	handler       func(*Chip8)
+}
+
+// New makes a chip ready to load a ROM into, configured by the options
+func New(opts ...Option) *Chip8 {
+	c := NewDefaultChip()
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.Initialise()
+	return c
+}
+
+// WithQuirks sets the quirks, see Quirks
+func WithQuirks(q Quirks) Option {
+	return func(c *Chip8) {
+		c.Quirks = q
+	}
+}
+
+// WithVariant sets the quirks to those of a variant
+func WithVariant(v Variant) Option {
+	return WithQuirks(v.Quirks())
+}
+
+// WithRandom replaces the random numbers used by CXNN, eg to make a run repeatable
+func WithRandom(f func() uint8) Option {
+	return func(c *Chip8) {
+		c.randomUintFunc = f
+	}
+}
+
+// WithSeed seeds the random numbers of CXNN, the same seed gives the same numbers every run
+func WithSeed(seed int64) Option {
+	return WithRandom(seededRandom(seed))
+}
+
+// WithDisplay presents the screen at the end of each RunFrame that drew to it
+func WithDisplay(d gfx.GFX) Option {
+	return func(c *Chip8) {
+		c.display = d
+	}
+}
+
+// WithAudio plays the buzzer
+func WithAudio(a Audio) Option {
+	return func(c *Chip8) {
+		c.audio = a
+	}
+}
+
+// WithInput polls the keypad at the start of each RunFrame
+func WithInput(in Input) Option {
+	return func(c *Chip8) {
+		c.input = in
+	}
+}
+
+// WithCyclesPerFrame sets how many instructions RunFrame executes, CyclesPerFrame by default
+func WithCyclesPerFrame(n int) Option {
+	return func(c *Chip8) {
+		c.cyclesPerFrame = n
+	}
+}
+
+// WithOpcode adds an instruction, or replaces a built in one, see RegisterOpcode
+func WithOpcode(pattern, mask uint16, h OpcodeHandler) Option {
+	return func(c *Chip8) {
+		c.RegisterOpcode(pattern, mask, h)
+	}
+}
+
+// RegisterOpcode runs h for every opcode where opcode&mask == pattern, eg 0x5001 with the mask 0xf00f for 5XY1. These
+// are checked before the built in instructions, and the most recently registered first.
+func (c *Chip8) RegisterOpcode(pattern, mask uint16, h OpcodeHandler) {
+	c.custom = append(c.custom, customOpcode{
+		pattern: pattern & mask,
+		mask:    mask,
+		handler: func(c *Chip8) {
+			if err := h(c); err != nil {
+				c.fault(err, "custom opcode")
+			}
+		},
+	})
+	c.InvalidateInstructionCache()
+}
+
+// lookup finds the handler for an opcode, custom ones first
+func (c *Chip8) lookup(opcode uint16) (func(*Chip8), error) {
+	for i := len(c.custom) - 1; i >= 0; i-- {
+		if oc := c.custom[i]; opcode&oc.mask == oc.pattern {
+			return oc.handler, nil
+		}
+	}
+	return c.LookupOpcode(opcode)
+}
@@ -0,0 +1,13 @@
+package chip
+
+// Quirks are the behaviours the different CHIP-8 interpreters disagree on. The zero value is how this emulator has
+// always behaved, see VariantCOSMAC for the original COSMAC VIP interpreter.
+type Quirks struct {
+	// WrapSprites draws the parts of a sprite that go past the edge of the display on the opposite edge, rather than
+	// clipping them. The position a sprite starts at is always wrapped.
+	WrapSprites bool
+
+	// DisplayWait makes DXYN wait for the vertical blank, the next 60Hz timer tick, before it draws, so at most one
+	// sprite is drawn each frame. The COSMAC VIP did this, and it cuts down on the flicker of games written for it.
+	DisplayWait bool
+}
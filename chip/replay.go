@@ -0,0 +1,169 @@
+package chip
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReplayOptions controls how a reference trace is replayed against a chip
+type ReplayOptions struct {
+	// CyclesPerTick is how many instructions run between each 60Hz timer tick, defaults to 8 (~500Hz)
+	CyclesPerTick int
+	// IgnoreTimers skips comparing the delay and sound timers, useful when the reference ran its timers off the
+	// wall clock rather than the instruction count
+	IgnoreTimers bool
+	// Context is how many of the previously executed instructions are included in a divergence, defaults to 8
+	Context int
+}
+
+// ReplayDivergence describes the first point a chip stopped matching the reference trace
+type ReplayDivergence struct {
+	Record   int // index of the record in the reference trace
+	Expected TraceRecord
+	Actual   TraceRecord
+	Fields   []string      // description of each field that differed
+	Previous []TraceRecord // the instructions executed leading up to the divergence, oldest first
+	Err      error         // set if the chip returned an error rather than executing the instruction
+}
+
+func (d *ReplayDivergence) Error() string {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "diverged from reference at cycle %d (record %d)\n", d.Expected.Cycle, d.Record)
+	if d.Err != nil {
+		fmt.Fprintf(b, "  error: %s\n", d.Err)
+	}
+	for _, f := range d.Fields {
+		fmt.Fprintf(b, "  %s\n", f)
+	}
+	fmt.Fprintf(b, "  expected: %s\n", d.Expected)
+	fmt.Fprintf(b, "  actual:   %s\n", d.Actual)
+	if len(d.Previous) > 0 {
+		fmt.Fprintf(b, "  previous instructions:\n")
+		for _, r := range d.Previous {
+			fmt.Fprintf(b, "    %s\n", r)
+		}
+	}
+
+	return b.String()
+}
+
+// Replay runs the chip in lockstep with a reference trace, comparing the state before every instruction with the
+// matching record. It returns nil once the whole trace has been replayed without a difference.
+//
+// Gaps in the reference (eg. from a filtered trace) are run through without comparing. Random numbers can't be
+// expected to match between emulators, so the result of each CXNN is taken from the record that follows it.
+func Replay(c *Chip8, ref *TraceReader, opts ReplayOptions) (*ReplayDivergence, error) {
+	if opts.CyclesPerTick <= 0 {
+		opts.CyclesPerTick = 8
+	}
+	if opts.Context <= 0 {
+		opts.Context = 8
+	}
+
+	defer func(r randomUintFunc) {
+		c.randomUintFunc = r
+	}(c.randomUintFunc)
+
+	var history []TraceRecord
+
+	want, err := ref.Next()
+	for i := 0; ; i++ {
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		next, nextErr := ref.Next()
+
+		for c.Cycles <= want.Cycle {
+			if c.Cycles > 0 && c.Cycles%uint64(opts.CyclesPerTick) == 0 {
+				c.TickTimers()
+			}
+
+			f, err := c.fetch()
+			got := c.traceRecord()
+			compare := c.Cycles == want.Cycle
+
+			if compare {
+				if fields := diffRecords(want, got, opts.IgnoreTimers); len(fields) > 0 {
+					return &ReplayDivergence{
+						Record:   i,
+						Expected: want,
+						Actual:   got,
+						Fields:   fields,
+						Previous: history,
+					}, nil
+				}
+
+				if got.OpCode&0xf000 == 0xc000 && nextErr == nil {
+					rnd := next.V[got.OpCode&0x0f00>>8]
+					c.randomUintFunc = func() uint8 {
+						return rnd
+					}
+				}
+			}
+
+			if err == nil {
+				f(c)
+				err = c.checkFault()
+			}
+			if err != nil {
+				if compare {
+					return &ReplayDivergence{
+						Record:   i,
+						Expected: want,
+						Actual:   got,
+						Previous: history,
+						Err:      err,
+					}, nil
+				}
+				return nil, fmt.Errorf("cycle %d, before reference record %d: %w", c.Cycles, i, err)
+			}
+			c.Cycles++
+
+			history = append(history, got)
+			if len(history) > opts.Context {
+				history = history[1:]
+			}
+		}
+
+		want, err = next, nextErr
+	}
+}
+
+// diffRecords describes every field that differs between two records
+func diffRecords(want, got TraceRecord, ignoreTimers bool) []string {
+	var diffs []string
+
+	if want.PC != got.PC {
+		diffs = append(diffs, fmt.Sprintf("PC: want %03x, got %03x", want.PC, got.PC))
+	}
+	if want.OpCode != got.OpCode {
+		diffs = append(diffs, fmt.Sprintf("opcode: want %04x (%s), got %04x (%s)", want.OpCode, Disassemble(want.OpCode), got.OpCode, Disassemble(got.OpCode)))
+	}
+	for r := range want.V {
+		if want.V[r] != got.V[r] {
+			diffs = append(diffs, fmt.Sprintf("V%X: want %02x, got %02x", r, want.V[r], got.V[r]))
+		}
+	}
+	if want.I != got.I {
+		diffs = append(diffs, fmt.Sprintf("I: want %03x, got %03x", want.I, got.I))
+	}
+	if want.SP != got.SP {
+		diffs = append(diffs, fmt.Sprintf("SP: want %x, got %x", want.SP, got.SP))
+	}
+	if !ignoreTimers {
+		if want.DelayTimer != got.DelayTimer {
+			diffs = append(diffs, fmt.Sprintf("DT: want %02x, got %02x", want.DelayTimer, got.DelayTimer))
+		}
+		if want.SoundTimer != got.SoundTimer {
+			diffs = append(diffs, fmt.Sprintf("ST: want %02x, got %02x", want.SoundTimer, got.SoundTimer))
+		}
+	}
+
+	return diffs
+}
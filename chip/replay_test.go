@@ -0,0 +1,99 @@
+package chip
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the ROMs that have a reference trace in testdata/traces, recorded by reference.py rather than this core. They're in
+// roms/ unless they were written for a trace, like flags, which are kept beside it.
+var referenceROMs = []string{"pong", "blinky", "invaders", "tank", "test_rom", "test_rom_2", "bc_test", "flags"}
+
+// referenceCycles is how long reference.py records each ROM for, unless it stops early
+const referenceCycles = 300
+
+func TestReplayReferenceTraces(t *testing.T) {
+	for _, rom := range referenceROMs {
+		t.Run(rom, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "traces", rom+".jsonl"))
+			require.NoError(t, err)
+			defer f.Close()
+
+			ref, err := NewTraceReader(f)
+			require.NoError(t, err)
+
+			romPath := filepath.Join("testdata", "traces", rom+".ch8")
+			if _, err := os.Stat(romPath); err != nil {
+				romPath = filepath.Join("..", "roms", rom+".ch8")
+			}
+			c := NewDefaultChip()
+			c.Initialise()
+			require.NoError(t, c.Load(romPath))
+
+			d, err := Replay(c, ref, ReplayOptions{})
+			require.NoError(t, err)
+			if d != nil {
+				t.Fatal(d)
+			}
+
+			// a trace that's short stopped on an instruction the reference couldn't run, and so should the chip
+			if c.Cycles < referenceCycles {
+				assert.Error(t, c.Step(), "the reference stopped at cycle %d", c.Cycles)
+			}
+		})
+	}
+}
+
+func TestReplayReportsFirstDivergence(t *testing.T) {
+	c := NewDefaultChip()
+	loadProgram(c, traceProgram)
+
+	// cycle 4 should have VA = 6 but the reference says 7
+	ref := strings.Join([]string{
+		`{"cycle":0,"pc":512,"opcode":27141,"v":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}`,
+		`{"cycle":1,"pc":514,"opcode":31233,"v":[0,0,0,0,0,0,0,0,0,0,5,0,0,0,0,0]}`,
+		`{"cycle":4,"pc":514,"opcode":31233,"v":[0,0,0,0,0,0,0,0,0,0,7,0,0,0,0,0],"i":768}`,
+	}, "\n")
+
+	tr, err := NewTraceReader(strings.NewReader(ref))
+	require.NoError(t, err)
+
+	d, err := Replay(c, tr, ReplayOptions{Context: 2})
+	require.NoError(t, err)
+	require.NotNil(t, d)
+
+	assert.Equal(t, 2, d.Record)
+	assert.Equal(t, uint64(4), d.Actual.Cycle)
+	assert.Equal(t, []string{"VA: want 07, got 06"}, d.Fields)
+	if assert.Len(t, d.Previous, 2) {
+		assert.Equal(t, uint64(2), d.Previous[0].Cycle)
+		assert.Equal(t, uint64(3), d.Previous[1].Cycle)
+	}
+	assert.Contains(t, d.Error(), "diverged from reference at cycle 4 (record 2)")
+}
+
+func TestReplayTakesRandomNumbersFromReference(t *testing.T) {
+	c := NewChip8(nil, func() uint8 { return 0xff })
+	loadProgram(c, []uint8{
+		0xc3, 0x0f, // 200: V3 = rand & 0x0f
+		0x12, 0x02, // 202: jump 0x202
+	})
+
+	ref := strings.Join([]string{
+		`{"cycle":0,"pc":512,"opcode":49935,"v":[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}`,
+		`{"cycle":1,"pc":514,"opcode":4610,"v":[0,0,0,9,0,0,0,0,0,0,0,0,0,0,0,0]}`,
+	}, "\n")
+
+	tr, err := NewTraceReader(strings.NewReader(ref))
+	require.NoError(t, err)
+
+	d, err := Replay(c, tr, ReplayOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, d)
+	assert.Equal(t, uint8(0xff), c.randomUintFunc())
+}
@@ -0,0 +1,175 @@
+package chip
+
+import (
+	"context"
+	"time"
+
+	"github.com/cuotos/chip8/gfx"
+)
+
+// Frame is the state of the chip at the end of a frame, published by a Runner
+type Frame struct {
+	Display    *gfx.Framebuffer // a copy of the screen, the receiver owns it
+	Drawn      bool             // whether anything was drawn since the last frame that was received
+	Keypad     [16]uint8
+	KeysPolled uint16 // the keys checked since the last frame that was received, see Chip8.KeysPolled
+	Cycles     int    // instructions executed since the last frame that was received
+	Idle       IdleState
+}
+
+type keyEvent struct {
+	key     uint8
+	pressed bool
+}
+
+// Runner runs a chip on its own goroutine, see Run. Anything else talks to it through channels, so the host can
+// draw, read input and so on without holding up the emulation, and the chip must not be touched directly while Run
+// is running. Use Do instead.
+type Runner struct {
+	// Interval is the time between frames, a 60th of a second by default. It can only be changed before Run.
+	Interval time.Duration
+
+	chip     *Chip8
+	keys     chan keyEvent
+	commands chan func(*Chip8)
+	frames   chan Frame
+	audio    chan bool
+	done     chan struct{} // closed when Run returns
+
+	// only used on the Run goroutine
+	paused  bool
+	speed   int
+	beeping bool
+	cycles  uint64 // Cycles at the last frame
+}
+
+func NewRunner(c *Chip8) *Runner {
+	return &Runner{
+		Interval: time.Second / 60,
+		chip:     c,
+		keys:     make(chan keyEvent, 64),
+		commands: make(chan func(*Chip8), 16),
+		frames:   make(chan Frame, 1),
+		audio:    make(chan bool, 1),
+		done:     make(chan struct{}),
+		speed:    1,
+		cycles:   c.Cycles,
+	}
+}
+
+// Run emulates the chip one frame at a time until ctx is done, when it returns nil, or the chip faults. Key presses
+// and commands are handled between frames as they arrive, frames are published on Frames.
+func (r *Runner) Run(ctx context.Context) error {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case e := <-r.keys:
+			r.chip.SetKey(e.key, e.pressed)
+
+		case cmd := <-r.commands:
+			cmd(r.chip)
+
+		case <-ticker.C:
+			for i := 0; i < r.speed && !r.paused; i++ {
+				if err := r.chip.RunFrame(); err != nil {
+					return err
+				}
+			}
+			r.publish()
+		}
+	}
+}
+
+// Frames receives a Frame every Interval. If the receiver falls behind it only gets the latest, merged with the ones
+// it missed, the emulation never waits for it.
+func (r *Runner) Frames() <-chan Frame {
+	return r.frames
+}
+
+// Audio receives whether the buzzer should be sounding whenever that changes
+func (r *Runner) Audio() <-chan bool {
+	return r.audio
+}
+
+// SetKey presses or releases a key, it satisfies input.Keypad
+func (r *Runner) SetKey(key uint8, pressed bool) {
+	select {
+	case r.keys <- keyEvent{key, pressed}:
+	case <-r.done:
+	}
+}
+
+// Do runs f on the Run goroutine between frames, where it can read or change the chip freely. It doesn't wait
+// for f to run, and f is dropped if Run has already returned.
+func (r *Runner) Do(f func(c *Chip8)) {
+	select {
+	case r.commands <- f:
+	case <-r.done:
+	}
+}
+
+// Pause stops or restarts the emulation, frames are still published while it's paused
+func (r *Runner) Pause(paused bool) {
+	r.Do(func(*Chip8) {
+		r.paused = paused
+	})
+}
+
+// SetSpeed runs n frames of emulation for every frame published, eg to fast forward
+func (r *Runner) SetSpeed(n int) {
+	r.Do(func(*Chip8) {
+		r.speed = n
+	})
+}
+
+// Reset resets the chip, see Chip8.Reset
+func (r *Runner) Reset(hard bool) {
+	r.Do(func(c *Chip8) {
+		c.Reset(hard)
+	})
+}
+
+// publish sends the frame and the state of the buzzer, replacing anything the receivers haven't taken yet
+func (r *Runner) publish() {
+	c := r.chip
+	if c.Cycles < r.cycles {
+		r.cycles = 0 // it's been reset
+	}
+	f := Frame{
+		Display:    c.Screen(),
+		Drawn:      c.DrawFlag,
+		Keypad:     c.Keypad,
+		KeysPolled: c.KeysPolled,
+		Cycles:     int(c.Cycles - r.cycles),
+		Idle:       c.Idle,
+	}
+	c.DrawFlag = false
+	c.KeysPolled = 0
+	r.cycles = c.Cycles
+
+	// only this goroutine sends, so once the stale value is taken there's room
+	select {
+	case old := <-r.frames:
+		f.Drawn = f.Drawn || old.Drawn
+		f.KeysPolled |= old.KeysPolled
+		f.Cycles += old.Cycles
+	default:
+	}
+	r.frames <- f
+
+	if on := c.SoundTimer > 0; on != r.beeping {
+		r.beeping = on
+		select {
+		case <-r.audio:
+		default:
+		}
+		r.audio <- on
+	}
+}
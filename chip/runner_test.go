@@ -0,0 +1,144 @@
+package chip
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startRunner runs a ROM on a Runner ticking every millisecond, stopping it at the end of the test
+func startRunner(t *testing.T, rom []byte) *Runner {
+	c := New()
+	require.NoError(t, c.LoadROM(rom))
+
+	r := NewRunner(c)
+	r.Interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- r.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-errs)
+	})
+	return r
+}
+
+// query runs f on the Run goroutine and waits for its answer
+func query[T any](r *Runner, f func(c *Chip8) T) T {
+	answer := make(chan T)
+	r.Do(func(c *Chip8) {
+		answer <- f(c)
+	})
+	return <-answer
+}
+
+func TestRunnerPublishesFrames(t *testing.T) {
+	// draw the 0 from the font at 0,0 then loop forever
+	r := startRunner(t, []byte{0xa0, 0x00, 0xd0, 0x05, 0x12, 0x04})
+
+	f := <-r.Frames()
+	for !f.Drawn {
+		f = <-r.Frames()
+	}
+	assert.True(t, f.Display.Get(0, 0))
+	assert.Greater(t, f.Cycles, 0)
+
+	// the frame is a copy, the receiver can do what it likes with it
+	f.Display.Clear()
+	assert.True(t, query(r, func(c *Chip8) bool { return c.Display.Get(0, 0) }))
+}
+
+func TestRunnerKeys(t *testing.T) {
+	// wait for a key and put it in V3, then loop forever
+	r := startRunner(t, []byte{0xf3, 0x0a, 0x12, 0x02})
+
+	f := <-r.Frames()
+	assert.Equal(t, uint16(0xffff), f.KeysPolled, "FX0A checks every key")
+
+	r.SetKey(0x5, true)
+	assert.Eventually(t, func() bool {
+		return query(r, func(c *Chip8) uint8 { return c.V[3] }) == 0x5
+	}, time.Second, time.Millisecond)
+
+	<-r.Frames() // may be from before the key was pressed
+	f = <-r.Frames()
+	assert.Equal(t, uint8(1), f.Keypad[0x5])
+}
+
+func TestRunnerAudio(t *testing.T) {
+	// sound timer = 3, then loop forever
+	r := startRunner(t, []byte{0x62, 0x03, 0xf2, 0x18, 0x12, 0x04})
+
+	assert.True(t, <-r.Audio())
+	assert.False(t, <-r.Audio())
+}
+
+func TestRunnerPauseAndReset(t *testing.T) {
+	// count up in V0 forever
+	r := startRunner(t, []byte{0x70, 0x01, 0x12, 0x00})
+
+	r.Pause(true)
+	v0 := func(c *Chip8) uint8 { return c.V[0] }
+	paused := query(r, v0)
+	<-r.Frames()
+	<-r.Frames()
+	assert.Equal(t, paused, query(r, v0))
+
+	r.Reset(false)
+	assert.Equal(t, uint8(0), query(r, v0))
+	assert.Equal(t, uint16(0x200), query(r, func(c *Chip8) uint16 { return c.PC }))
+
+	r.Pause(false)
+	assert.Eventually(t, func() bool {
+		return query(r, v0) > 0
+	}, time.Second, time.Millisecond)
+}
+
+// TestRunnerConcurrentHost drives the runner from several goroutines at once, run it with -race
+func TestRunnerConcurrentHost(t *testing.T) {
+	// draw a sprite at V0,V1 and move it along, forever
+	r := startRunner(t, []byte{0xa0, 0x00, 0xd0, 0x15, 0x70, 0x01, 0x12, 0x00})
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			r.SetKey(uint8(i%16), i%2 == 0)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			r.SetSpeed(1 + i%4)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			f := <-r.Frames()
+			f.Display.Set(0, 0, true)
+		}
+	}()
+	wg.Wait()
+}
+
+func TestRunnerFault(t *testing.T) {
+	c := New()
+	require.NoError(t, c.LoadROM([]byte{0x00, 0xee})) // return with nothing on the stack
+	r := NewRunner(c)
+	r.Interval = time.Millisecond
+
+	assert.Error(t, r.Run(context.Background()))
+
+	// once it's stopped nothing blocks
+	r.SetKey(0x1, true)
+	r.Pause(true)
+}
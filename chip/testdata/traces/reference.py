@@ -0,0 +1,303 @@
+#!/usr/bin/env python3
+"""Records the reference traces in this directory with an interpreter that shares nothing with the Go core.
+
+It follows the instruction semantics of Octo (https://github.com/JohnEarnest/Octo, emulator.js) with the quirks set to
+match this emulator's defaults:
+
+    shift quirk       on   8XY6 and 8XYE shift VX in place and ignore VY
+    load/store quirk  on   FX55 and FX65 leave I alone
+    clip quirk        on   sprites are clipped at the edges of the display rather than wrapped
+    jump quirk        off  BNNN jumps to NNN + V0
+    logic quirk       off  8XY1, 8XY2 and 8XY3 leave VF alone
+    vblank quirk      off  sprites are drawn straight away
+
+Flags are written after the result, so VF holds the flag when it's also the destination. The font is the usual one
+from the COSMAC VIP at 0x000. Nothing is pressed on the keypad, the timers tick every 8 instructions as Replay expects,
+and CXNN draws from a seeded generator since Replay takes the result of each from the record that follows it.
+
+A record holds the state before its instruction runs, one for each instruction that runs. An instruction Octo would
+stop on (an unknown opcode, machine code, a bad return, an address outside memory) ends the trace without a record.
+
+    python3 reference.py                 record every ROM that has a trace here
+    python3 reference.py pong tank       record just these
+"""
+
+import json
+import os
+import random
+import sys
+
+HERE = os.path.dirname(os.path.abspath(__file__))
+ROMS = os.path.join(HERE, "..", "..", "..", "roms")
+
+CYCLES = 300
+CYCLES_PER_TICK = 8
+SEED = 1
+
+FONT = [
+    0xF0, 0x90, 0x90, 0x90, 0xF0,  # 0
+    0x20, 0x60, 0x20, 0x20, 0x70,  # 1
+    0xF0, 0x10, 0xF0, 0x80, 0xF0,  # 2
+    0xF0, 0x10, 0xF0, 0x10, 0xF0,  # 3
+    0x90, 0x90, 0xF0, 0x10, 0x10,  # 4
+    0xF0, 0x80, 0xF0, 0x10, 0xF0,  # 5
+    0xF0, 0x80, 0xF0, 0x90, 0xF0,  # 6
+    0xF0, 0x10, 0x20, 0x40, 0x40,  # 7
+    0xF0, 0x90, 0xF0, 0x90, 0xF0,  # 8
+    0xF0, 0x90, 0xF0, 0x10, 0xF0,  # 9
+    0xF0, 0x90, 0xF0, 0x90, 0x90,  # A
+    0xE0, 0x90, 0xE0, 0x90, 0xE0,  # B
+    0xF0, 0x80, 0x80, 0x80, 0xF0,  # C
+    0xE0, 0x90, 0x90, 0x90, 0xE0,  # D
+    0xF0, 0x80, 0xF0, 0x80, 0xF0,  # E
+    0xF0, 0x80, 0xF0, 0x80, 0x80,  # F
+]
+
+
+class Halt(Exception):
+    """Octo would stop here, with a message saying why"""
+
+
+class Chip8:
+    def __init__(self, rom, seed):
+        if len(rom) > 4096 - 0x200:
+            raise Halt("ROM is too big")
+        self.mem = bytearray(4096)
+        self.mem[0:len(FONT)] = bytes(FONT)
+        self.mem[0x200:0x200 + len(rom)] = rom
+        self.v = [0] * 16
+        self.i = 0
+        self.pc = 0x200
+        self.stack = []
+        self.dt = 0
+        self.st = 0
+        self.keys = [False] * 16
+        self.hires = False
+        self.pixels = set()
+        self.rng = random.Random(seed)
+
+    def size(self):
+        return (128, 64) if self.hires else (64, 32)
+
+    def read(self, addr):
+        if not 0 <= addr < len(self.mem):
+            raise Halt("read from %04x, outside memory" % addr)
+        return self.mem[addr]
+
+    def write(self, addr, value):
+        if not 0 <= addr < len(self.mem):
+            raise Halt("write to %04x, outside memory" % addr)
+        self.mem[addr] = value
+
+    def record(self, cycle):
+        return {
+            "cycle": cycle,
+            "pc": self.pc,
+            "opcode": self.read(self.pc) << 8 | self.read(self.pc + 1),
+            "v": list(self.v),
+            "i": self.i,
+            "sp": len(self.stack),
+            "dt": self.dt,
+            "st": self.st,
+        }
+
+    def tick(self):
+        self.dt = max(self.dt - 1, 0)
+        self.st = max(self.st - 1, 0)
+
+    def step(self):
+        op = self.read(self.pc) << 8 | self.read(self.pc + 1)
+        x = op >> 8 & 0xF
+        y = op >> 4 & 0xF
+        n = op & 0xF
+        nn = op & 0xFF
+        nnn = op & 0xFFF
+        v = self.v
+        next_pc = self.pc + 2
+
+        if op == 0x00E0:
+            self.pixels.clear()
+        elif op == 0x00EE:
+            if not self.stack:
+                raise Halt("return with nothing on the stack")
+            next_pc = self.stack.pop()
+        elif op == 0x00FE:
+            self.hires = False
+            self.pixels.clear()
+        elif op == 0x00FF:
+            self.hires = True
+            self.pixels.clear()
+        elif op >> 12 == 0x0:
+            raise Halt("machine code %04x isn't supported" % op)
+        elif op >> 12 == 0x1:
+            next_pc = nnn
+        elif op >> 12 == 0x2:
+            if len(self.stack) >= 16:
+                raise Halt("stack overflow")
+            self.stack.append(next_pc)
+            next_pc = nnn
+        elif op >> 12 == 0x3:
+            if v[x] == nn:
+                next_pc += 2
+        elif op >> 12 == 0x4:
+            if v[x] != nn:
+                next_pc += 2
+        elif op >> 12 == 0x5 and n == 0:
+            if v[x] == v[y]:
+                next_pc += 2
+        elif op >> 12 == 0x6:
+            v[x] = nn
+        elif op >> 12 == 0x7:
+            v[x] = (v[x] + nn) & 0xFF
+        elif op >> 12 == 0x8:
+            self.math(x, y, n)
+        elif op >> 12 == 0x9 and n == 0:
+            if v[x] != v[y]:
+                next_pc += 2
+        elif op >> 12 == 0xA:
+            self.i = nnn
+        elif op >> 12 == 0xB:
+            next_pc = nnn + v[0]
+        elif op >> 12 == 0xC:
+            v[x] = self.rng.randrange(256) & nn
+        elif op >> 12 == 0xD:
+            self.sprite(v[x], v[y], n)
+        elif op >> 12 == 0xE and nn == 0x9E:
+            if self.keys[v[x] & 0xF]:
+                next_pc += 2
+        elif op >> 12 == 0xE and nn == 0xA1:
+            if not self.keys[v[x] & 0xF]:
+                next_pc += 2
+        elif op >> 12 == 0xF:
+            next_pc = self.misc(x, nn, next_pc)
+        else:
+            raise Halt("unknown opcode %04x" % op)
+
+        if not 0 <= next_pc < len(self.mem) - 1:
+            raise Halt("PC moved to %04x, outside memory" % next_pc)
+        self.pc = next_pc
+
+    def math(self, x, y, n):
+        v = self.v
+        if n == 0x0:
+            v[x] = v[y]
+        elif n == 0x1:
+            v[x] |= v[y]
+        elif n == 0x2:
+            v[x] &= v[y]
+        elif n == 0x3:
+            v[x] ^= v[y]
+        elif n == 0x4:
+            t = v[x] + v[y]
+            v[x] = t & 0xFF
+            v[0xF] = 1 if t > 0xFF else 0
+        elif n == 0x5:
+            t = v[x] - v[y]
+            v[x] = t & 0xFF
+            v[0xF] = 0 if t < 0 else 1
+        elif n == 0x6:
+            flag = v[x] & 0x1
+            v[x] = v[x] >> 1
+            v[0xF] = flag
+        elif n == 0x7:
+            t = v[y] - v[x]
+            v[x] = t & 0xFF
+            v[0xF] = 0 if t < 0 else 1
+        elif n == 0xE:
+            flag = v[x] >> 7
+            v[x] = v[x] << 1 & 0xFF
+            v[0xF] = flag
+        else:
+            raise Halt("unknown math opcode 8%x%x%x" % (x, y, n))
+
+    def misc(self, x, nn, next_pc):
+        v = self.v
+        if nn == 0x07:
+            v[x] = self.dt
+        elif nn == 0x0A:
+            pressed = [k for k in range(16) if self.keys[k]]
+            if not pressed:
+                return self.pc  # run it again until a key is down
+            v[x] = pressed[0]
+        elif nn == 0x15:
+            self.dt = v[x]
+        elif nn == 0x18:
+            self.st = v[x]
+        elif nn == 0x1E:
+            self.i = (self.i + v[x]) & 0xFFFF
+        elif nn == 0x29:
+            self.i = (v[x] & 0xF) * 5
+        elif nn == 0x33:
+            self.write(self.i, v[x] // 100)
+            self.write(self.i + 1, v[x] // 10 % 10)
+            self.write(self.i + 2, v[x] % 10)
+        elif nn == 0x55:
+            for r in range(x + 1):
+                self.write(self.i + r, v[r])
+        elif nn == 0x65:
+            for r in range(x + 1):
+                v[r] = self.read(self.i + r)
+        else:
+            raise Halt("unknown misc opcode f%x%02x" % (x, nn))
+        return next_pc
+
+    def sprite(self, vx, vy, n):
+        width, height = self.size()
+        rows, cols = (16, 16) if n == 0 else (n, 8)
+        x0, y0 = vx % width, vy % height
+        collided = 0
+        for row in range(rows):
+            if n == 0:
+                bits = self.read(self.i + row * 2) << 8 | self.read(self.i + row * 2 + 1)
+            else:
+                bits = self.read(self.i + row)
+            py = y0 + row
+            if py >= height:
+                break
+            for col in range(cols):
+                px = x0 + col
+                if px >= width:
+                    break
+                if bits >> (cols - 1 - col) & 1:
+                    if (px, py) in self.pixels:
+                        self.pixels.remove((px, py))
+                        collided = 1
+                    else:
+                        self.pixels.add((px, py))
+        self.v[0xF] = collided
+
+
+def record(name):
+    # a ROM made for a trace lives beside it, the rest are in roms/
+    path = os.path.join(HERE, name + ".ch8")
+    if not os.path.exists(path):
+        path = os.path.join(ROMS, name + ".ch8")
+    with open(path, "rb") as f:
+        chip = Chip8(f.read(), SEED)
+
+    lines = []
+    for cycle in range(CYCLES):
+        if cycle > 0 and cycle % CYCLES_PER_TICK == 0:
+            chip.tick()
+        rec = chip.record(cycle)
+        try:
+            chip.step()
+        except Halt as e:
+            print("%s stopped at cycle %d, pc %03x: %s" % (name, cycle, rec["pc"], e), file=sys.stderr)
+            break
+        lines.append(json.dumps(rec, separators=(",", ":")))
+
+    with open(os.path.join(HERE, name + ".jsonl"), "w") as f:
+        f.write("".join(line + "\n" for line in lines))
+
+
+def main():
+    names = sys.argv[1:]
+    if not names:
+        names = sorted(f[:-len(".jsonl")] for f in os.listdir(HERE) if f.endswith(".jsonl"))
+    for name in names:
+        record(name)
+
+
+if __name__ == "__main__":
+    main()
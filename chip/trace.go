@@ -0,0 +1,254 @@
+package chip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type TraceFormat int
+
+const (
+	// TraceJSON writes one JSON object per line
+	TraceJSON TraceFormat = iota
+	// TraceBinary writes a short header followed by fixed size little endian records
+	TraceBinary
+)
+
+// traceMagic starts every binary trace so readers can tell the two formats apart
+var traceMagic = []byte("C8TR\x01")
+
+// TraceRecord is the state of the chip immediately before the instruction at PC is executed
+type TraceRecord struct {
+	Cycle      uint64    `json:"cycle"`
+	PC         uint16    `json:"pc"`
+	OpCode     uint16    `json:"opcode"`
+	Mnemonic   string    `json:"mnemonic"`
+	V          [16]uint8 `json:"v"`
+	I          uint16    `json:"i"`
+	SP         uint16    `json:"sp"`
+	DelayTimer uint8     `json:"dt"`
+	SoundTimer uint8     `json:"st"`
+}
+
+// binaryTraceRecord is the on disk layout of a TraceRecord in the binary format, the mnemonic is not stored as it can
+// be recreated from the opcode
+type binaryTraceRecord struct {
+	Cycle      uint64
+	PC         uint16
+	OpCode     uint16
+	V          [16]uint8
+	I          uint16
+	SP         uint16
+	DelayTimer uint8
+	SoundTimer uint8
+}
+
+// Equal compares everything apart from the mnemonic, which is derived from the opcode anyway
+func (r TraceRecord) Equal(o TraceRecord) bool {
+	return r.Cycle == o.Cycle &&
+		r.PC == o.PC &&
+		r.OpCode == o.OpCode &&
+		r.V == o.V &&
+		r.I == o.I &&
+		r.SP == o.SP &&
+		r.DelayTimer == o.DelayTimer &&
+		r.SoundTimer == o.SoundTimer
+}
+
+func (r TraceRecord) String() string {
+	return fmt.Sprintf("cycle:%d pc:%03x oc:%04x %-16s V:%x I:%03x SP:%x DT:%02x ST:%02x",
+		r.Cycle, r.PC, r.OpCode, r.Mnemonic, r.V, r.I, r.SP, r.DelayTimer, r.SoundTimer)
+}
+
+// TraceFilter limits which instructions are written to a trace. Both ranges are inclusive, an end of 0 means no
+// upper limit.
+type TraceFilter struct {
+	PCStart    uint16
+	PCEnd      uint16
+	CycleStart uint64
+	CycleEnd   uint64
+}
+
+func (f TraceFilter) Match(r TraceRecord) bool {
+	if r.PC < f.PCStart || (f.PCEnd != 0 && r.PC > f.PCEnd) {
+		return false
+	}
+	if r.Cycle < f.CycleStart || (f.CycleEnd != 0 && r.Cycle > f.CycleEnd) {
+		return false
+	}
+	return true
+}
+
+// Tracer writes a TraceRecord for every instruction the chip executes that matches its Filter
+type Tracer struct {
+	Filter TraceFilter
+
+	w             io.Writer
+	format        TraceFormat
+	headerWritten bool
+}
+
+func NewTracer(w io.Writer, format TraceFormat) *Tracer {
+	return &Tracer{
+		w:      w,
+		format: format,
+	}
+}
+
+func (t *Tracer) Trace(r TraceRecord) error {
+	if !t.Filter.Match(r) {
+		return nil
+	}
+
+	switch t.format {
+	case TraceJSON:
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = t.w.Write(append(b, '\n'))
+		return err
+
+	case TraceBinary:
+		if !t.headerWritten {
+			if _, err := t.w.Write(traceMagic); err != nil {
+				return err
+			}
+			t.headerWritten = true
+		}
+		return binary.Write(t.w, binary.LittleEndian, binaryTraceRecord{
+			Cycle:      r.Cycle,
+			PC:         r.PC,
+			OpCode:     r.OpCode,
+			V:          r.V,
+			I:          r.I,
+			SP:         r.SP,
+			DelayTimer: r.DelayTimer,
+			SoundTimer: r.SoundTimer,
+		})
+	}
+
+	return fmt.Errorf("unknown trace format: %d", t.format)
+}
+
+// TraceReader reads back a trace written in either format
+type TraceReader struct {
+	r      *bufio.Reader
+	format TraceFormat
+}
+
+func NewTraceReader(r io.Reader) (*TraceReader, error) {
+	tr := &TraceReader{
+		r:      bufio.NewReader(r),
+		format: TraceJSON,
+	}
+
+	head, err := tr.r.Peek(len(traceMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(head, traceMagic) {
+		tr.format = TraceBinary
+		if _, err := tr.r.Discard(len(traceMagic)); err != nil {
+			return nil, err
+		}
+	}
+
+	return tr, nil
+}
+
+// Next returns the next record in the trace, or io.EOF once there are none left
+func (tr *TraceReader) Next() (TraceRecord, error) {
+	var r TraceRecord
+
+	if tr.format == TraceBinary {
+		var br binaryTraceRecord
+		if err := binary.Read(tr.r, binary.LittleEndian, &br); err != nil {
+			return r, err
+		}
+		r = TraceRecord{
+			Cycle:      br.Cycle,
+			PC:         br.PC,
+			OpCode:     br.OpCode,
+			Mnemonic:   Disassemble(br.OpCode),
+			V:          br.V,
+			I:          br.I,
+			SP:         br.SP,
+			DelayTimer: br.DelayTimer,
+			SoundTimer: br.SoundTimer,
+		}
+		return r, nil
+	}
+
+	for {
+		line, err := tr.r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return r, err
+			}
+			continue
+		}
+		return r, json.Unmarshal(line, &r)
+	}
+}
+
+// TraceDivergence is the first point two traces disagree. Either record is nil if that trace ended early.
+type TraceDivergence struct {
+	Index int
+	A     *TraceRecord
+	B     *TraceRecord
+}
+
+func (d TraceDivergence) String() string {
+	describe := func(r *TraceRecord) string {
+		if r == nil {
+			return "<end of trace>"
+		}
+		return r.String()
+	}
+	return fmt.Sprintf("traces diverge at record %d\n  a: %s\n  b: %s", d.Index, describe(d.A), describe(d.B))
+}
+
+// DiffTraces reads both traces to the end and returns the first record that differs, or nil if they are the same
+func DiffTraces(a, b *TraceReader) (*TraceDivergence, error) {
+	for i := 0; ; i++ {
+		ra, errA := a.Next()
+		if errA != nil && errA != io.EOF {
+			return nil, errA
+		}
+		rb, errB := b.Next()
+		if errB != nil && errB != io.EOF {
+			return nil, errB
+		}
+
+		switch {
+		case errA == io.EOF && errB == io.EOF:
+			return nil, nil
+		case errA == io.EOF:
+			return &TraceDivergence{Index: i, B: &rb}, nil
+		case errB == io.EOF:
+			return &TraceDivergence{Index: i, A: &ra}, nil
+		case !ra.Equal(rb):
+			return &TraceDivergence{Index: i, A: &ra, B: &rb}, nil
+		}
+	}
+}
+
+// traceRecord captures the current state of the chip, OpCode must already have been fetched
+func (c *Chip8) traceRecord() TraceRecord {
+	return TraceRecord{
+		Cycle:      c.Cycles,
+		PC:         c.PC,
+		OpCode:     c.OpCode,
+		Mnemonic:   Disassemble(c.OpCode),
+		V:          c.V,
+		I:          c.I,
+		SP:         c.SP,
+		DelayTimer: c.DelayTimer,
+		SoundTimer: c.SoundTimer,
+	}
+}
@@ -0,0 +1,179 @@
+package chip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var traceProgram = []uint8{
+	0x6a, 0x05, // 200: VA = 5
+	0x7a, 0x01, // 202: VA += 1
+	0xa3, 0x00, // 204: I = 0x300
+	0x12, 0x02, // 206: jump 0x202
+}
+
+func runTraced(t *testing.T, tracer *Tracer, cycles int) {
+	c := NewDefaultChip()
+	loadProgram(c, traceProgram)
+	c.Tracer = tracer
+
+	for i := 0; i < cycles; i++ {
+		if !assert.NoError(t, c.EmulateCycle()) {
+			return
+		}
+	}
+}
+
+func readAll(t *testing.T, r io.Reader) []TraceRecord {
+	tr, err := NewTraceReader(r)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	var records []TraceRecord
+	for {
+		rec, err := tr.Next()
+		if err == io.EOF {
+			return records
+		}
+		if !assert.NoError(t, err) {
+			return records
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestTraceJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	runTraced(t, NewTracer(buf, TraceJSON), 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, `{"cycle":1,"pc":514,"opcode":31233,"mnemonic":"ADD VA, 0x01","v":[0,0,0,0,0,0,0,0,0,0,5,0,0,0,0,0],"i":0,"sp":0,"dt":0,"st":0}`, lines[1])
+
+	records := readAll(t, strings.NewReader(buf.String()))
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, uint16(0x204), records[2].PC)
+		assert.Equal(t, uint8(6), records[2].V[0xa])
+	}
+}
+
+func TestTraceBinaryRoundTrip(t *testing.T) {
+	jsonBuf := &bytes.Buffer{}
+	binBuf := &bytes.Buffer{}
+	runTraced(t, NewTracer(jsonBuf, TraceJSON), 10)
+	runTraced(t, NewTracer(binBuf, TraceBinary), 10)
+
+	assert.True(t, bytes.HasPrefix(binBuf.Bytes(), traceMagic))
+	assert.Equal(t, readAll(t, jsonBuf), readAll(t, binBuf))
+}
+
+func TestTraceFilter(t *testing.T) {
+	tcs := []struct {
+		Filter         TraceFilter
+		ExpectedCycles []uint64
+	}{
+		{TraceFilter{}, []uint64{0, 1, 2, 3, 4, 5, 6, 7}},
+		{TraceFilter{PCStart: 0x202, PCEnd: 0x202}, []uint64{1, 4, 7}},
+		{TraceFilter{PCStart: 0x204}, []uint64{2, 3, 5, 6}},
+		{TraceFilter{CycleStart: 3, CycleEnd: 5}, []uint64{3, 4, 5}},
+		{TraceFilter{PCStart: 0x202, PCEnd: 0x202, CycleStart: 2}, []uint64{4, 7}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(fmt.Sprintf("%+v", tc.Filter), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			tracer := NewTracer(buf, TraceJSON)
+			tracer.Filter = tc.Filter
+			runTraced(t, tracer, 8)
+
+			cycles := []uint64{}
+			for _, r := range readAll(t, buf) {
+				cycles = append(cycles, r.Cycle)
+			}
+			assert.Equal(t, tc.ExpectedCycles, cycles)
+		})
+	}
+}
+
+func TestDiffTraces(t *testing.T) {
+	a := &bytes.Buffer{}
+	b := &bytes.Buffer{}
+	runTraced(t, NewTracer(a, TraceJSON), 5)
+	runTraced(t, NewTracer(b, TraceBinary), 5)
+
+	ra, _ := NewTraceReader(bytes.NewReader(a.Bytes()))
+	rb, _ := NewTraceReader(bytes.NewReader(b.Bytes()))
+	d, err := DiffTraces(ra, rb)
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+
+	// change VA in the fourth record of one run
+	changed := strings.Replace(a.String(), `"cycle":3,"pc":518,"opcode":4610,"mnemonic":"JP 0x202","v":[0,0,0,0,0,0,0,0,0,0,6`, `"cycle":3,"pc":518,"opcode":4610,"mnemonic":"JP 0x202","v":[0,0,0,0,0,0,0,0,0,0,9`, 1)
+	ra, _ = NewTraceReader(strings.NewReader(changed))
+	rb, _ = NewTraceReader(bytes.NewReader(b.Bytes()))
+	d, err = DiffTraces(ra, rb)
+	if assert.NoError(t, err) && assert.NotNil(t, d) {
+		assert.Equal(t, 3, d.Index)
+		assert.Equal(t, uint8(9), d.A.V[0xa])
+		assert.Equal(t, uint8(6), d.B.V[0xa])
+	}
+
+	// one trace stopping early is a divergence too
+	short := &bytes.Buffer{}
+	runTraced(t, NewTracer(short, TraceJSON), 4)
+	ra, _ = NewTraceReader(short)
+	rb, _ = NewTraceReader(bytes.NewReader(b.Bytes()))
+	d, err = DiffTraces(ra, rb)
+	if assert.NoError(t, err) && assert.NotNil(t, d) {
+		assert.Equal(t, 4, d.Index)
+		assert.Nil(t, d.A)
+		assert.NotNil(t, d.B)
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	tcs := []struct {
+		OpCode   uint16
+		Expected string
+	}{
+		{0x00e0, "CLS"},
+		{0x00ee, "RET"},
+		{0x00fe, "LOW"},
+		{0x00ff, "HIGH"},
+		{0x0123, "SYS 0x123"},
+		{0x124e, "JP 0x24e"},
+		{0x22d4, "CALL 0x2d4"},
+		{0x362b, "SE V6, 0x2b"},
+		{0x462b, "SNE V6, 0x2b"},
+		{0x56a0, "SE V6, VA"},
+		{0x56a1, "DW 0x56a1"},
+		{0x6a02, "LD VA, 0x02"},
+		{0x7a0a, "ADD VA, 0x0a"},
+		{0x8ab4, "ADD VA, VB"},
+		{0x8ab6, "SHR VA"},
+		{0x8ab8, "DW 0x8ab8"},
+		{0x96a0, "SNE V6, VA"},
+		{0xaff0, "LD I, 0xff0"},
+		{0xb555, "JP V0, 0x555"},
+		{0xc712, "RND V7, 0x12"},
+		{0xdcd3, "DRW VC, VD, 3"},
+		{0xe59e, "SKP V5"},
+		{0xefa1, "SKNP VF"},
+		{0xfa07, "LD VA, DT"},
+		{0xf30a, "LD V3, K"},
+		{0xf233, "LD B, V2"},
+		{0xf555, "LD [I], V5"},
+		{0xf565, "LD V5, [I]"},
+		{0xf5ff, "DW 0xf5ff"},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.Expected, Disassemble(tc.OpCode))
+	}
+}
@@ -0,0 +1,126 @@
+package gfx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter sits between the chip's framebuffer and a backend to hide flicker. Games erase a sprite by drawing it again
+// (sprites are XORed onto the display) then draw it somewhere else, so any frame can catch a sprite half drawn. A
+// filter is given every frame, 60 times a second, and returns the frame to present in its place.
+type Filter interface {
+	Filter(frame *Framebuffer) *Framebuffer
+}
+
+// Decay simulates the phosphor of a CRT, a pixel that goes out fades away over a number of frames rather than
+// disappearing straight away
+type Decay struct {
+	Frames int
+
+	out Framebuffer
+	off []int // frames since each pixel was last lit
+}
+
+func NewDecay(frames int) *Decay {
+	return &Decay{Frames: frames}
+}
+
+func (d *Decay) Filter(frame *Framebuffer) *Framebuffer {
+	if d.out.Width != frame.Width || d.out.Height != frame.Height {
+		d.out.CopyFrom(frame)
+		d.out.Intensity = make([]uint8, len(frame.Pixels))
+		d.off = make([]int, len(frame.Pixels))
+		for i := range d.off {
+			d.off[i] = d.Frames + 1
+		}
+	}
+
+	for i, p := range frame.Pixels {
+		if p != 0 {
+			d.out.Pixels[i] = p
+			d.off[i] = 0
+		} else if d.off[i] <= d.Frames {
+			d.off[i]++
+		}
+
+		// fading evenly to nothing, after Frames frames it's out
+		if d.off[i] > d.Frames {
+			d.out.Pixels[i] = 0
+			d.out.Intensity[i] = 0
+			continue
+		}
+		d.out.Intensity[i] = uint8(255 * (d.Frames + 1 - d.off[i]) / (d.Frames + 1))
+	}
+
+	return &d.out
+}
+
+// MaxOf shows every pixel lit in any of the last N frames, so a sprite that's been erased to be redrawn is still shown
+type MaxOf struct {
+	N int
+
+	history []Framebuffer // the last N frames, oldest first
+	out     Framebuffer
+}
+
+func NewMaxOf(n int) *MaxOf {
+	return &MaxOf{N: n}
+}
+
+func (m *MaxOf) Filter(frame *Framebuffer) *Framebuffer {
+	if len(m.history) > 0 && (m.history[0].Width != frame.Width || m.history[0].Height != frame.Height) {
+		m.history = nil
+	}
+
+	if len(m.history) < m.N {
+		m.history = append(m.history, Framebuffer{})
+	} else {
+		// reuse the oldest
+		m.history = append(m.history[1:], m.history[0])
+	}
+	m.history[len(m.history)-1].CopyFrom(frame)
+
+	m.out.CopyFrom(frame)
+	for _, h := range m.history[:len(m.history)-1] {
+		for i, p := range h.Pixels {
+			if p > m.out.Pixels[i] {
+				m.out.Pixels[i] = p
+			}
+		}
+	}
+
+	return &m.out
+}
+
+// FilterNames describes the filters ParseFilter understands
+const FilterNames = "none, decay:N (fade out over N frames) or max:N (any pixel lit in the last N frames)"
+
+// ParseFilter reads a filter written as its name and a number of frames, eg "decay:4", see FilterNames. "none" or
+// nothing at all is no filter, which is nil.
+func ParseFilter(s string) (Filter, error) {
+	if s == "" || s == "none" {
+		return nil, nil
+	}
+
+	name, arg := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		name, arg = s[:i], s[i+1:]
+	}
+
+	n := 2
+	if arg != "" {
+		var err error
+		if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid number of frames in filter %q", s)
+		}
+	}
+
+	switch name {
+	case "decay":
+		return NewDecay(n), nil
+	case "max":
+		return NewMaxOf(n), nil
+	}
+	return nil, fmt.Errorf("unknown filter %q, expected %s", s, FilterNames)
+}
@@ -0,0 +1,108 @@
+package gfx
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecay(t *testing.T) {
+	f := NewFramebuffer(4, 1)
+	d := NewDecay(3)
+
+	f.Pixels = []uint8{1, 0, 2, 0}
+	out := d.Filter(f)
+	assert.Equal(t, []uint8{1, 0, 2, 0}, out.Pixels)
+	assert.Equal(t, []uint8{255, 0, 255, 0}, out.Intensity)
+
+	// the first pixel goes out and fades over 3 frames, keeping its value while it does
+	f.Pixels = []uint8{0, 0, 2, 0}
+	var fade []uint8
+	for i := 0; i < 4; i++ {
+		out = d.Filter(f)
+		fade = append(fade, out.Intensity[0])
+		if i < 3 {
+			assert.Equal(t, uint8(1), out.Pixels[0])
+		}
+	}
+	assert.Equal(t, []uint8{191, 127, 63, 0}, fade)
+	assert.Equal(t, uint8(0), out.Pixels[0])
+	assert.Equal(t, uint8(255), out.Intensity[2])
+
+	// lighting it again is straight back to full
+	f.Pixels = []uint8{1, 0, 2, 0}
+	out = d.Filter(f)
+	assert.Equal(t, uint8(255), out.Intensity[0])
+
+	// the chip's framebuffer is never changed
+	assert.Nil(t, f.Intensity)
+}
+
+func TestMaxOf(t *testing.T) {
+	f := NewFramebuffer(4, 1)
+	m := NewMaxOf(2)
+
+	// a sprite erased and redrawn one pixel over, the frame in between is blank
+	frames := [][]uint8{
+		{1, 1, 0, 0},
+		{0, 0, 0, 0},
+		{0, 1, 1, 0},
+		{0, 1, 1, 0},
+		{0, 0, 0, 0},
+		{0, 0, 0, 0},
+	}
+	expected := [][]uint8{
+		{1, 1, 0, 0},
+		{1, 1, 0, 0},
+		{0, 1, 1, 0},
+		{0, 1, 1, 0},
+		{0, 1, 1, 0},
+		{0, 0, 0, 0},
+	}
+
+	for i, pixels := range frames {
+		copy(f.Pixels, pixels)
+		assert.Equal(t, expected[i], m.Filter(f).Pixels, "frame %d", i)
+	}
+}
+
+func TestFilterResize(t *testing.T) {
+	for _, filter := range []Filter{NewDecay(2), NewMaxOf(2)} {
+		filter.Filter(NewFramebuffer(Width, Height))
+		out := filter.Filter(NewFramebuffer(128, 64))
+		assert.Len(t, out.Pixels, 128*64)
+	}
+}
+
+func TestFramebufferColourWithIntensity(t *testing.T) {
+	p := Palette{{0, 0, 0, 255}, {200, 100, 50, 255}}
+	f := NewFramebuffer(3, 1)
+	f.Pixels = []uint8{1, 1, 0}
+	assert.Equal(t, p[1], f.Colour(0, p))
+
+	f.Intensity = []uint8{255, 128, 0}
+	assert.Equal(t, p[1], f.Colour(0, p))
+	assert.Equal(t, color.RGBA{100, 50, 25, 255}, f.Colour(1, p))
+	assert.Equal(t, p[0], f.Colour(2, p))
+}
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter("decay:4")
+	require.NoError(t, err)
+	assert.Equal(t, 4, f.(*Decay).Frames)
+
+	f, err = ParseFilter("max")
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.(*MaxOf).N)
+
+	f, err = ParseFilter("none")
+	require.NoError(t, err)
+	assert.Nil(t, f)
+
+	for _, bad := range []string{"blur", "decay:0", "max:x"} {
+		_, err := ParseFilter(bad)
+		assert.Error(t, err, bad)
+	}
+}
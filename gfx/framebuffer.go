@@ -0,0 +1,137 @@
+package gfx
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"strings"
+)
+
+const (
+	// Width and Height are the resolution of the original CHIP-8 display
+	Width  = 64
+	Height = 32
+
+	// HiResWidth and HiResHeight are the resolution of the SUPER-CHIP high resolution mode
+	HiResWidth  = 128
+	HiResHeight = 64
+)
+
+// Framebuffer is the state of the display, owned by the chip and handed to a GFX to be presented. Pixels are stored a
+// row at a time, 1 for lit and 0 for unlit.
+type Framebuffer struct {
+	Width  int
+	Height int
+	Pixels []uint8
+
+	// Intensity is only set by the filters that fade pixels out, see Filter. It's how bright each pixel is, from 0 to
+	// 255, and a pixel that's fading keeps the value it was lit with.
+	Intensity []uint8
+}
+
+func NewFramebuffer(width, height int) *Framebuffer {
+	return &Framebuffer{
+		Width:  width,
+		Height: height,
+		Pixels: make([]uint8, width*height),
+	}
+}
+
+// Get reports if the pixel at x, y is lit, anything off the display is unlit
+func (f *Framebuffer) Get(x, y int) bool {
+	if x < 0 || y < 0 || x >= f.Width || y >= f.Height {
+		return false
+	}
+	return f.Pixels[y*f.Width+x] != 0
+}
+
+// Set lights or clears the pixel at x, y, anything off the display is ignored
+func (f *Framebuffer) Set(x, y int, on bool) {
+	if x < 0 || y < 0 || x >= f.Width || y >= f.Height {
+		return
+	}
+	var v uint8
+	if on {
+		v = 1
+	}
+	f.Pixels[y*f.Width+x] = v
+}
+
+func (f *Framebuffer) Clear() {
+	for i := range f.Pixels {
+		f.Pixels[i] = 0
+	}
+}
+
+// Colour is the colour of pixel i, allowing for its intensity
+func (f *Framebuffer) Colour(i int, p Palette) color.RGBA {
+	if f.Intensity == nil {
+		return p.Colour(f.Pixels[i])
+	}
+	return p.Shade(f.Pixels[i], f.Intensity[i])
+}
+
+// Resize changes the resolution of the display, clearing it
+func (f *Framebuffer) Resize(width, height int) {
+	f.Width = width
+	f.Height = height
+	f.Intensity = nil
+	if cap(f.Pixels) >= width*height {
+		f.Pixels = f.Pixels[:width*height]
+		f.Clear()
+		return
+	}
+	f.Pixels = make([]uint8, width*height)
+}
+
+// CopyFrom makes f an exact copy of src, reusing its pixels where it can
+func (f *Framebuffer) CopyFrom(src *Framebuffer) {
+	f.Width = src.Width
+	f.Height = src.Height
+	if cap(f.Pixels) < len(src.Pixels) {
+		f.Pixels = make([]uint8, len(src.Pixels))
+	}
+	f.Pixels = f.Pixels[:len(src.Pixels)]
+	copy(f.Pixels, src.Pixels)
+
+	if src.Intensity == nil {
+		f.Intensity = nil
+		return
+	}
+	if cap(f.Intensity) < len(src.Intensity) {
+		f.Intensity = make([]uint8, len(src.Intensity))
+	}
+	f.Intensity = f.Intensity[:len(src.Intensity)]
+	copy(f.Intensity, src.Intensity)
+}
+
+// String renders the display as text, one line per row with '#' for a lit pixel and '.' for an unlit one
+func (f *Framebuffer) String() string {
+	b := &strings.Builder{}
+	b.Grow((f.Width + 1) * f.Height)
+	for y := 0; y < f.Height; y++ {
+		for _, p := range f.Pixels[y*f.Width : (y+1)*f.Width] {
+			if p != 0 {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Hash identifies what's on the display, two displays of the same size with the same pixels lit have the same hash.
+// Intensity is left out.
+func (f *Framebuffer) Hash() string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%dx%d:", f.Width, f.Height)
+	for _, p := range f.Pixels {
+		if p != 0 {
+			p = 1
+		}
+		h.Write([]byte{p})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,66 @@
+package gfx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFramebuffer(t *testing.T) {
+	f := NewFramebuffer(Width, Height)
+
+	f.Set(0, 0, true)
+	f.Set(1, 1, true)
+	f.Set(63, 31, true)
+	f.Set(64, 0, true)
+	f.Set(-1, 5, true)
+
+	assert.True(t, f.Get(1, 1))
+	assert.False(t, f.Get(1, 0))
+	assert.False(t, f.Get(64, 0))
+
+	lines := strings.Split(strings.TrimSuffix(f.String(), "\n"), "\n")
+	if assert.Len(t, lines, 32) {
+		assert.Equal(t, "#"+strings.Repeat(".", 63), lines[0])
+		assert.Equal(t, ".#"+strings.Repeat(".", 62), lines[1])
+		assert.Equal(t, strings.Repeat(".", 63)+"#", lines[31])
+	}
+
+	f.Set(1, 1, false)
+	assert.False(t, f.Get(1, 1))
+
+	f.Clear()
+	assert.NotContains(t, f.String(), "#")
+}
+
+func TestFramebufferResize(t *testing.T) {
+	f := NewFramebuffer(Width, Height)
+	f.Set(5, 5, true)
+
+	f.Resize(128, 64)
+	assert.Len(t, f.Pixels, 128*64)
+	assert.False(t, f.Get(5, 5))
+	f.Set(127, 63, true)
+	assert.True(t, f.Get(127, 63))
+
+	f.Resize(Width, Height)
+	assert.Len(t, f.Pixels, Width*Height)
+	assert.NotContains(t, f.String(), "#")
+}
+
+func TestFramebufferHash(t *testing.T) {
+	a := NewFramebuffer(Width, Height)
+	b := NewFramebuffer(Width, Height)
+	assert.Equal(t, a.Hash(), b.Hash())
+	assert.Len(t, a.Hash(), 16)
+
+	a.Set(3, 4, true)
+	assert.NotEqual(t, a.Hash(), b.Hash())
+	b.Set(3, 4, true)
+	b.Intensity = make([]uint8, len(b.Pixels))
+	assert.Equal(t, a.Hash(), b.Hash(), "only which pixels are lit counts")
+
+	// the same pixels on a different shape of display
+	assert.NotEqual(t, NewFramebuffer(64, 32).Hash(), NewFramebuffer(128, 16).Hash())
+}
@@ -1,9 +1,6 @@
 package gfx
 
+// GFX is a display backend. The chip owns the framebuffer, a backend only has to show the frame it's given.
 type GFX interface {
-	SetPixel(pixel, value uint16)
-	GetPixel(pixel uint16) uint16
-	Draw()
-	Clear()
+	Present(frame *Framebuffer)
 }
-
@@ -0,0 +1,101 @@
+package gfx
+
+import (
+	"bytes"
+	"errors"
+	"image/gif"
+	"io"
+	"os"
+)
+
+// minGIFDelay is the shortest delay, in 100ths of a second, a frame of a GIF can have. Most viewers slow anything
+// shorter right down, so a frame that would be shown for less time is dropped and the one after it shown in its place.
+const minGIFDelay = 2
+
+// GIFRecorder records the display to an animated GIF. It should be given the frame 60 times a second, identical frames
+// in a row are only stored once and shown for longer.
+type GIFRecorder struct {
+	Scale   int
+	Palette Palette
+	Scaler  Scaler // how frames are enlarged to Scale, Nearest if nil
+
+	anim  gif.GIF
+	last  Framebuffer
+	ticks int // 60Hz frames seen so far
+	shown int // 100ths of a second taken by every image but the last
+}
+
+func NewGIFRecorder(scale int, p Palette) *GIFRecorder {
+	return &GIFRecorder{
+		Scale:   scale,
+		Palette: p,
+	}
+}
+
+// AddFrame records one 60Hz frame
+func (r *GIFRecorder) AddFrame(f *Framebuffer) {
+	r.ticks++
+
+	if len(r.anim.Image) > 0 && r.last.Width == f.Width && r.last.Height == f.Height && bytes.Equal(r.last.Pixels, f.Pixels) {
+		return
+	}
+
+	img := paletted(Render(f, r.Scale, r.Palette, r.Scaler))
+	r.last.CopyFrom(f)
+
+	if len(r.anim.Image) > 0 {
+		delay := r.delayUntil(r.ticks - 1)
+		if delay < minGIFDelay {
+			// shown too briefly to keep, this frame takes its place
+			r.anim.Image[len(r.anim.Image)-1] = img
+			return
+		}
+		r.anim.Delay[len(r.anim.Delay)-1] = delay
+		r.shown += delay
+	}
+
+	r.anim.Image = append(r.anim.Image, img)
+	r.anim.Delay = append(r.anim.Delay, 0)
+}
+
+// delayUntil is how long the last image is shown for if the next one starts at tick. Delays are worked out from the
+// total time so far so the rounding to 100ths of a second doesn't add up over a long recording.
+func (r *GIFRecorder) delayUntil(tick int) int {
+	return (tick*100+30)/60 - r.shown
+}
+
+// Frames is the number of distinct images recorded so far
+func (r *GIFRecorder) Frames() int {
+	return len(r.anim.Image)
+}
+
+// Encode writes everything recorded so far as a GIF
+func (r *GIFRecorder) Encode(w io.Writer) error {
+	anim := r.anim
+	if len(anim.Image) == 0 {
+		return errors.New("no frames have been recorded")
+	}
+
+	anim.Delay = append([]int(nil), r.anim.Delay...)
+	delay := r.delayUntil(r.ticks)
+	if delay < minGIFDelay {
+		delay = minGIFDelay
+	}
+	anim.Delay[len(anim.Delay)-1] = delay
+
+	return gif.EncodeAll(w, &anim)
+}
+
+// Save writes everything recorded so far to a GIF file
+func (r *GIFRecorder) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Encode(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,91 @@
+package gfx
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGIFRecorder(t *testing.T) {
+	f := NewFramebuffer(Width, Height)
+	r := NewGIFRecorder(2, DefaultPalette)
+
+	// a second of the same frame, then a frame that changes every 3 ticks for half a second
+	for i := 0; i < 60; i++ {
+		r.AddFrame(f)
+	}
+	for i := 0; i < 30; i++ {
+		f.Set(i/3, 0, true)
+		r.AddFrame(f)
+	}
+	assert.Equal(t, 11, r.Frames())
+
+	b := &bytes.Buffer{}
+	require.NoError(t, r.Encode(b))
+
+	anim, err := gif.DecodeAll(b)
+	require.NoError(t, err)
+	assert.Len(t, anim.Image, 11)
+	assert.Equal(t, 128, anim.Image[0].Bounds().Dx())
+
+	// 3 ticks is 5 100ths of a second, and the whole thing adds up to exactly 1.5s
+	assert.Equal(t, []int{100, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5}, anim.Delay)
+}
+
+func TestGIFRecorderDelaysDontDrift(t *testing.T) {
+	f := NewFramebuffer(Width, Height)
+	r := NewGIFRecorder(1, DefaultPalette)
+
+	// a new frame every other tick is 3.33 100ths of a second, the delays alternate so they add up
+	for i := 0; i < 120; i++ {
+		f.Set(i/2, 0, true)
+		r.AddFrame(f)
+	}
+
+	b := &bytes.Buffer{}
+	require.NoError(t, r.Encode(b))
+	anim, err := gif.DecodeAll(b)
+	require.NoError(t, err)
+
+	total := 0
+	for _, d := range anim.Delay {
+		assert.Contains(t, []int{3, 4}, d)
+		total += d
+	}
+	assert.Equal(t, 60, len(anim.Delay))
+	assert.Equal(t, 200, total)
+}
+
+func TestGIFRecorderDropsFramesTooShortToShow(t *testing.T) {
+	f := NewFramebuffer(Width, Height)
+	r := NewGIFRecorder(1, DefaultPalette)
+
+	// a new frame every tick is 1.67 100ths of a second, too short for most viewers, so every other one is dropped
+	for i := 0; i < 60; i++ {
+		f.Set(i, 0, true)
+		r.AddFrame(f)
+	}
+
+	b := &bytes.Buffer{}
+	require.NoError(t, r.Encode(b))
+	anim, err := gif.DecodeAll(b)
+	require.NoError(t, err)
+
+	total := 0
+	for _, d := range anim.Delay {
+		assert.GreaterOrEqual(t, d, minGIFDelay)
+		total += d
+	}
+	assert.Equal(t, 100, total)
+
+	// the last frame is always kept
+	assert.Equal(t, uint8(1), anim.Image[len(anim.Image)-1].ColorIndexAt(59, 0))
+}
+
+func TestGIFRecorderNothingRecorded(t *testing.T) {
+	r := NewGIFRecorder(1, DefaultPalette)
+	assert.Error(t, r.Encode(&bytes.Buffer{}))
+}
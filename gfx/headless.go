@@ -0,0 +1,22 @@
+package gfx
+
+// Headless doesn't draw anywhere, it keeps a copy of the last frame presented, for tests and for running ROMs without a
+// window
+type Headless struct {
+	Last   Framebuffer
+	Frames int // number of frames presented
+}
+
+func NewHeadlessGFX() *Headless {
+	return &Headless{}
+}
+
+func (h *Headless) Present(frame *Framebuffer) {
+	h.Last.CopyFrom(frame)
+	h.Frames++
+}
+
+// String renders the last frame presented as text, see Framebuffer.String
+func (h *Headless) String() string {
+	return h.Last.String()
+}
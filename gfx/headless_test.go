@@ -0,0 +1,24 @@
+package gfx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeadless(t *testing.T) {
+	f := NewFramebuffer(Width, Height)
+	h := NewHeadlessGFX()
+
+	f.Set(2, 3, true)
+	h.Present(f)
+
+	// it keeps a copy of what was presented, not the live frame
+	f.Clear()
+	assert.True(t, h.Last.Get(2, 3))
+	assert.Equal(t, 1, h.Frames)
+
+	h.Present(f)
+	assert.NotContains(t, h.String(), "#")
+	assert.Equal(t, 2, h.Frames)
+}
@@ -0,0 +1,143 @@
+package gfx
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+)
+
+// MessageTime is how long a HUD message stays on screen
+const MessageTime = 2 * time.Second
+
+var (
+	hudText    = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	hudKeyDown = color.RGBA{0xff, 0xd0, 0x40, 0xff}
+)
+
+// keypadLayout is the COSMAC VIP keypad, as it's shown in the HUD
+var keypadLayout = [4][4]uint8{
+	{0x1, 0x2, 0x3, 0xc},
+	{0x4, 0x5, 0x6, 0xd},
+	{0x7, 0x8, 0x9, 0xe},
+	{0xa, 0x0, 0xb, 0xf},
+}
+
+// HUD is a status overlay drawn over the display once it's been scaled up. The frontend fills in the fields and calls
+// Draw after the frame, so anything saved from the frame alone doesn't include it.
+type HUD struct {
+	Visible bool
+
+	FPS            float64 // frames presented a second
+	CyclesPerFrame int     // instructions run in the last frame
+	Paused         bool
+	FastForward    bool
+	Keys           [16]bool // which keys of the keypad are down
+
+	message      string
+	messageUntil time.Time
+}
+
+// Message shows some text at the bottom of the HUD for MessageTime, replacing any message already there
+func (h *HUD) Message(now time.Time, text string) {
+	h.message = text
+	h.messageUntil = now.Add(MessageTime)
+}
+
+// SetKeys copies the state of the keypad, any key that isn't 0 is down
+func (h *HUD) SetKeys(keypad [16]uint8) {
+	for i, v := range keypad {
+		h.Keys[i] = v != 0
+	}
+}
+
+// Draw draws the HUD over img, if it's Visible. Text is scaled to suit the size of img, each pixel of the font is a
+// whole number of pixels.
+func (h *HUD) Draw(img *image.RGBA, now time.Time) {
+	if !h.Visible {
+		return
+	}
+
+	unit := img.Rect.Dx() / 320
+	if unit < 1 {
+		unit = 1
+	}
+	margin := 2 * unit
+	b := img.Rect.Inset(margin)
+
+	h.label(img, b.Min, unit, fmt.Sprintf("%.0f FPS %d IPF", h.FPS, h.CyclesPerFrame))
+
+	state := ""
+	switch {
+	case h.Paused:
+		state = "PAUSED"
+	case h.FastForward:
+		state = "FAST FORWARD"
+	}
+	if state != "" {
+		h.label(img, image.Pt(b.Max.X-(textWidth(state)+2)*unit, b.Min.Y), unit, state)
+	}
+
+	if h.message != "" && now.Before(h.messageUntil) {
+		h.label(img, image.Pt(b.Min.X, b.Max.Y-(glyphHeight+2)*unit), unit, h.message)
+	}
+
+	h.drawKeypad(img, b.Max, unit)
+}
+
+// label draws text in a darkened box with its top left corner at p
+func (h *HUD) label(img *image.RGBA, p image.Point, unit int, text string) {
+	box := image.Rect(0, 0, (textWidth(text)+2)*unit, (glyphHeight+2)*unit).Add(p)
+	darken(img, box)
+	drawText(img, p.X+unit, p.Y+unit, unit, text, hudText)
+}
+
+// drawKeypad draws the 16 keys with its bottom right corner at p, the keys that are down are highlighted
+func (h *HUD) drawKeypad(img *image.RGBA, p image.Point, unit int) {
+	cell := (glyphWidth + 2) * unit
+	if cellHeight := (glyphHeight + 2) * unit; cellHeight > cell {
+		cell = cellHeight
+	}
+	pad := image.Rect(0, 0, 4*cell, 4*cell).Add(p.Sub(image.Pt(4*cell, 4*cell)))
+	darken(img, pad)
+
+	for row, keys := range keypadLayout {
+		for col, key := range keys {
+			r := image.Rect(0, 0, cell, cell).Add(pad.Min).Add(image.Pt(col*cell, row*cell))
+			c := hudText
+			if h.Keys[key] {
+				fill(img, r.Inset(unit/2), hudKeyDown)
+				c = color.RGBA{0, 0, 0, 0xff}
+			}
+			x := r.Min.X + (cell-glyphWidth*unit)/2
+			y := r.Min.Y + (cell-glyphHeight*unit)/2
+			drawText(img, x, y, unit, fmt.Sprintf("%X", key), c)
+		}
+	}
+}
+
+// darken quarters the brightness of a rectangle of img, so text over it can be read whatever's underneath
+func darken(img *image.RGBA, r image.Rectangle) {
+	r = r.Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(r.Min.X, y):img.PixOffset(r.Max.X, y)]
+		for i := 0; i < len(row); i += 4 {
+			row[i] /= 4
+			row[i+1] /= 4
+			row[i+2] /= 4
+		}
+	}
+}
+
+// Overlay renders the frame like Render, with the HUD drawn over it
+func (h *HUD) Overlay(f *Framebuffer, scale int, p Palette, s Scaler, now time.Time) *image.RGBA {
+	if scale < 1 {
+		scale = 1
+	}
+	if s == nil {
+		s = Nearest{}
+	}
+	img := s.Scale(f.RGBA(p), scale)
+	h.Draw(img, now)
+	return img
+}
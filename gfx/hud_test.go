@@ -0,0 +1,116 @@
+package gfx
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrawText(t *testing.T) {
+	img := pattern(
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+	)
+	drawText(img, 0, 0, 1, "1a", testOn)
+	assert.Equal(t, ""+
+		".#...#..\n"+
+		"##..#.#.\n"+
+		".#..###.\n"+
+		".#..#.#.\n"+
+		"###.#.#.\n", picture(img))
+
+	assert.Equal(t, 7, textWidth("1A"))
+	assert.Equal(t, 0, textWidth(""))
+}
+
+func TestFontHasHexDigits(t *testing.T) {
+	for _, r := range "0123456789ABCDEF" {
+		_, ok := font[r]
+		assert.True(t, ok, "%c", r)
+	}
+}
+
+// lit counts the pixels in a rectangle of img that aren't black
+func lit(img *image.RGBA, r image.Rectangle) int {
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if c := img.RGBAAt(x, y); c.R != 0 || c.G != 0 || c.B != 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestHUD(t *testing.T) {
+	now := time.Now()
+	h := &HUD{FPS: 60, CyclesPerFrame: 8}
+
+	// nothing is drawn until it's visible
+	img := image.NewRGBA(image.Rect(0, 0, 640, 320))
+	h.Draw(img, now)
+	assert.Zero(t, lit(img, img.Rect))
+
+	h.Visible = true
+	h.Draw(img, now)
+	topLeft := image.Rect(0, 0, 100, 20)
+	assert.NotZero(t, lit(img, topLeft), "FPS")
+	assert.Zero(t, lit(img, image.Rect(540, 0, 640, 20)), "no state")
+	assert.Zero(t, lit(img, image.Rect(0, 300, 100, 320)), "no message")
+
+	h.Paused = true
+	h.Message(now, "SAVED")
+	img = image.NewRGBA(img.Rect)
+	h.Draw(img, now)
+	assert.NotZero(t, lit(img, image.Rect(540, 0, 640, 20)), "paused")
+	assert.NotZero(t, lit(img, image.Rect(0, 300, 100, 320)), "message")
+
+	// the message goes away
+	img = image.NewRGBA(img.Rect)
+	h.Draw(img, now.Add(MessageTime))
+	assert.Zero(t, lit(img, image.Rect(0, 300, 100, 320)))
+}
+
+func TestHUDKeypad(t *testing.T) {
+	h := &HUD{Visible: true}
+	img := image.NewRGBA(image.Rect(0, 0, 640, 320))
+
+	// the keypad is in the bottom right, 4 cells of 14 pixels at this size
+	pad := image.Rect(640-4-56, 320-4-56, 640-4, 320-4)
+	cell := func(row, col int) image.Rectangle {
+		return image.Rect(0, 0, 14, 14).Add(pad.Min).Add(image.Pt(col*14, row*14))
+	}
+
+	h.Draw(img, time.Now())
+	up := lit(img, cell(3, 3))
+	assert.NotZero(t, up, "F is drawn")
+
+	// F is down, its cell is filled in
+	var keypad [16]uint8
+	keypad[0xf] = 1
+	h.SetKeys(keypad)
+	img = image.NewRGBA(img.Rect)
+	h.Draw(img, time.Now())
+	assert.Greater(t, lit(img, cell(3, 3)), up)
+	assert.Equal(t, hudKeyDown, img.RGBAAt(cell(3, 3).Min.X+1, cell(3, 3).Min.Y+1))
+	assert.Equal(t, up, lit(img, cell(0, 0)), "1 is still up")
+}
+
+func TestHUDOverlay(t *testing.T) {
+	f := NewFramebuffer(Width, Height)
+	h := &HUD{}
+
+	img := h.Overlay(f, 10, DefaultPalette, nil, time.Now())
+	assert.Equal(t, image.Rect(0, 0, 640, 320), img.Rect)
+	assert.Zero(t, lit(img, img.Rect))
+
+	h.Visible = true
+	img = h.Overlay(f, 10, DefaultPalette, nil, time.Now())
+	assert.NotZero(t, lit(img, img.Rect))
+}
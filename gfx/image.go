@@ -0,0 +1,68 @@
+package gfx
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+)
+
+// Image renders the frame with each pixel scaled up to a scale x scale square
+func (f *Framebuffer) Image(scale int, p Palette) *image.Paletted {
+	if scale < 1 {
+		scale = 1
+	}
+
+	colours := make(color.Palette, len(p))
+	for i, c := range p {
+		colours[i] = c
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, f.Width*scale, f.Height*scale), colours)
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			v := f.Pixels[y*f.Width+x]
+			if int(v) >= len(p) {
+				// the same as Palette.Colour
+				v = uint8(len(p) - 1)
+			}
+			if v == 0 {
+				continue
+			}
+
+			for sy := 0; sy < scale; sy++ {
+				row := img.Pix[(y*scale+sy)*img.Stride:]
+				for sx := 0; sx < scale; sx++ {
+					row[x*scale+sx] = v
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// WritePNG encodes the frame as a PNG, see Render
+func WritePNG(w io.Writer, f *Framebuffer, scale int, p Palette, s Scaler) error {
+	return png.Encode(w, Render(f, scale, p, s))
+}
+
+// SavePNG writes the frame to a PNG file, see Render
+func SavePNG(path string, f *Framebuffer, scale int, p Palette, s Scaler) error {
+	return SaveImagePNG(path, Render(f, scale, p, s))
+}
+
+// SaveImagePNG writes an image that's already been rendered to a PNG file, eg one with the HUD drawn over it
+func SaveImagePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,36 @@
+package gfx
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePNG(t *testing.T) {
+	f := NewFramebuffer(Width, Height)
+	f.Set(0, 0, true)
+	f.Set(63, 31, true)
+
+	p := Palette{{0x10, 0x20, 0x30, 255}, {0xff, 0xb0, 0x00, 255}}
+	b := &bytes.Buffer{}
+	require.NoError(t, WritePNG(b, f, 4, p, nil))
+
+	img, err := png.Decode(b)
+	require.NoError(t, err)
+
+	assert.Equal(t, 64*4, img.Bounds().Dx())
+	assert.Equal(t, 32*4, img.Bounds().Dy())
+
+	on := color.RGBAModel.Convert(p[1])
+	off := color.RGBAModel.Convert(p[0])
+	assert.Equal(t, on, color.RGBAModel.Convert(img.At(0, 0)))
+	assert.Equal(t, on, color.RGBAModel.Convert(img.At(3, 3)))
+	assert.Equal(t, off, color.RGBAModel.Convert(img.At(4, 0)))
+	assert.Equal(t, off, color.RGBAModel.Convert(img.At(0, 4)))
+	assert.Equal(t, on, color.RGBAModel.Convert(img.At(64*4-1, 32*4-1)))
+	assert.Equal(t, off, color.RGBAModel.Convert(img.At(64*4-5, 32*4-1)))
+}
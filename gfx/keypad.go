@@ -0,0 +1,108 @@
+package gfx
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+var (
+	keypadBackground = color.RGBA{0x10, 0x10, 0x10, 0xff}
+	keypadKey        = color.RGBA{0x40, 0x40, 0x40, 0xff}
+	keypadPolled     = color.RGBA{0x30, 0x60, 0xc0, 0xff}
+)
+
+// VirtualKeypad is a clickable COSMAC VIP keypad drawn beside or below the display, for playing with a mouse or touch
+// screen. Keys that are down are lit and the keys the ROM is checking are highlighted, so it's easy to see what it's
+// waiting for.
+type VirtualKeypad struct {
+	Visible bool
+	Down    [16]bool
+	Polled  uint16 // a bit for each key the ROM checked, see chip.Chip8.KeysPolled
+}
+
+// SetKeys copies the state of the keypad, any key that isn't 0 is down
+func (k *VirtualKeypad) SetKeys(keypad [16]uint8) {
+	for i, v := range keypad {
+		k.Down[i] = v != 0
+	}
+}
+
+// SplitKeypad divides an area between the display and a square keypad, putting the keypad to the right if there's
+// room for the display beside it and below otherwise
+func SplitKeypad(area image.Rectangle) (display, keypad image.Rectangle) {
+	w, h := area.Dx(), area.Dy()
+
+	if w >= 2*h {
+		side := h
+		if w/3 < side {
+			side = w / 3
+		}
+		keypad = image.Rect(area.Max.X-side, area.Min.Y, area.Max.X, area.Min.Y+side)
+		keypad = keypad.Add(image.Pt(0, (h-side)/2))
+		return image.Rect(area.Min.X, area.Min.Y, area.Max.X-side, area.Max.Y), keypad
+	}
+
+	side := w
+	if h/3 < side {
+		side = h / 3
+	}
+	keypad = image.Rect(area.Min.X, area.Max.Y-side, area.Min.X+side, area.Max.Y)
+	keypad = keypad.Add(image.Pt((w-side)/2, 0))
+	return image.Rect(area.Min.X, area.Min.Y, area.Max.X, area.Max.Y-side), keypad
+}
+
+// keyRect is where a key is on a keypad drawn size x size
+func keyRect(size, row, col int) image.Rectangle {
+	cell := size / 4
+	return image.Rect(col*cell, row*cell, (col+1)*cell, (row+1)*cell)
+}
+
+// KeyAt is the key at a point on a keypad drawn size x size, false if it's in the gap between keys or outside
+func KeyAt(size int, p image.Point) (uint8, bool) {
+	for row, keys := range keypadLayout {
+		for col, key := range keys {
+			if p.In(keyInset(keyRect(size, row, col))) {
+				return key, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// keyInset leaves a gap around each key
+func keyInset(r image.Rectangle) image.Rectangle {
+	return r.Inset(r.Dx() / 16)
+}
+
+// Image draws the keypad size x size
+func (k *VirtualKeypad) Image(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	fill(img, img.Rect, keypadBackground)
+
+	unit := size / 4 / (glyphHeight * 3)
+	if unit < 1 {
+		unit = 1
+	}
+
+	for row, keys := range keypadLayout {
+		for col, key := range keys {
+			r := keyInset(keyRect(size, row, col))
+
+			background, text := keypadKey, hudText
+			switch {
+			case k.Down[key]:
+				background, text = hudKeyDown, color.RGBA{0, 0, 0, 0xff}
+			case k.Polled&(1<<key) != 0:
+				background = keypadPolled
+			}
+			fill(img, r, background)
+
+			x := r.Min.X + (r.Dx()-glyphWidth*unit)/2
+			y := r.Min.Y + (r.Dy()-glyphHeight*unit)/2
+			drawText(img, x, y, unit, fmt.Sprintf("%X", key), text)
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,91 @@
+package gfx
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitKeypad(t *testing.T) {
+	// wide, the keypad goes to the right and leaves the display its usual 2:1
+	display, keypad := SplitKeypad(image.Rect(0, 0, 960, 320))
+	assert.Equal(t, image.Rect(0, 0, 640, 320), display)
+	assert.Equal(t, image.Rect(640, 0, 960, 320), keypad)
+
+	// not wide enough for that, it goes below
+	display, keypad = SplitKeypad(image.Rect(0, 0, 640, 600))
+	assert.Equal(t, image.Rect(0, 0, 640, 400), display)
+	assert.Equal(t, image.Rect(220, 400, 420, 600), keypad)
+
+	// not quite wide enough for a full height keypad, it takes a third and is centred
+	display, keypad = SplitKeypad(image.Rect(0, 0, 750, 300))
+	assert.Equal(t, image.Rect(0, 0, 500, 300), display)
+	assert.Equal(t, image.Rect(500, 25, 750, 275), keypad)
+}
+
+func TestKeyAt(t *testing.T) {
+	for _, tc := range []struct {
+		P   image.Point
+		Key uint8
+		Ok  bool
+	}{
+		{image.Pt(50, 50), 0x1, true},
+		{image.Pt(350, 50), 0xc, true},
+		{image.Pt(150, 350), 0x0, true},
+		{image.Pt(399, 399), 0xf, false}, // in the gap around F
+		{image.Pt(350, 350), 0xf, true},
+		{image.Pt(250, 150), 0x6, true},
+		{image.Pt(-1, 50), 0, false},
+		{image.Pt(50, 400), 0, false},
+	} {
+		key, ok := KeyAt(400, tc.P)
+		assert.Equal(t, tc.Ok, ok, "%v", tc.P)
+		if tc.Ok {
+			assert.Equal(t, tc.Key, key, "%v", tc.P)
+		}
+	}
+}
+
+func TestVirtualKeypadImage(t *testing.T) {
+	k := &VirtualKeypad{}
+	k.Down[0x5] = true
+	k.Polled = 1<<0x5 | 1<<0x6
+
+	img := k.Image(400)
+	assert.Equal(t, image.Rect(0, 0, 400, 400), img.Rect)
+
+	// the corner of each key shows its state
+	corner := func(row, col int) image.Point {
+		return keyInset(keyRect(400, row, col)).Min
+	}
+	assert.Equal(t, keypadKey, img.RGBAAt(corner(0, 0).X, corner(0, 0).Y))
+	assert.Equal(t, hudKeyDown, img.RGBAAt(corner(1, 1).X, corner(1, 1).Y), "5 is down, that shows over it being polled")
+	assert.Equal(t, keypadPolled, img.RGBAAt(corner(1, 2).X, corner(1, 2).Y))
+	assert.Equal(t, keypadBackground, img.RGBAAt(0, 0), "the gap between keys")
+
+	// and every key has its label, black on a key that's down
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			label := hudText
+			if row == 1 && col == 1 {
+				label = testOff
+			}
+			assert.NotZero(t, countColour(img, keyInset(keyRect(400, row, col)), label), "%d, %d", row, col)
+		}
+	}
+}
+
+// countColour counts the pixels of a colour in a rectangle of img
+func countColour(img *image.RGBA, r image.Rectangle, c color.RGBA) int {
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if img.RGBAAt(x, y) == c {
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,85 @@
+package gfx
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Palette is the colour of each pixel value. Index 0 is unlit and 1 lit, four colour palettes are for multi-plane
+// displays where 2 is a pixel lit on the second plane and 3 one lit on both. A pixel value past the end of the palette
+// uses its last colour.
+type Palette []color.RGBA
+
+// Colour is the colour of a pixel value
+func (p Palette) Colour(v uint8) color.RGBA {
+	if int(v) >= len(p) {
+		return p[len(p)-1]
+	}
+	return p[v]
+}
+
+// Shade is the colour of a pixel value at an intensity from 0 to 255, blended between the unlit colour and its own
+func (p Palette) Shade(v uint8, intensity uint8) color.RGBA {
+	off, on := p[0], p.Colour(v)
+	blend := func(a, b uint8) uint8 {
+		return uint8((int(a)*(255-int(intensity)) + int(b)*int(intensity) + 127) / 255)
+	}
+	return color.RGBA{blend(off.R, on.R), blend(off.G, on.G), blend(off.B, on.B), blend(off.A, on.A)}
+}
+
+func rgb(v uint32) color.RGBA {
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}
+}
+
+// Palettes are the named palettes
+var Palettes = map[string]Palette{
+	"classic":       {rgb(0x000000), rgb(0x00ff00)},
+	"amber":         {rgb(0x1a0f00), rgb(0xffb000)},
+	"lcd":           {rgb(0xc7cfa1), rgb(0x2b3a20)},
+	"high-contrast": {rgb(0x000000), rgb(0xffffff)},
+	"octo":          {rgb(0x996600), rgb(0xffcc00), rgb(0xff6600), rgb(0x662200)},
+	"gameboy":       {rgb(0x9bbc0f), rgb(0x306230), rgb(0x8bac0f), rgb(0x0f380f)},
+}
+
+// DefaultPalette is green on black, how it's always looked
+var DefaultPalette = Palettes["classic"]
+
+// PaletteNames lists the named palettes, sorted
+func PaletteNames() []string {
+	var names []string
+	for name := range Palettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParsePalette reads either the name of a palette or a list of comma separated hex colours, eg "000000,00ff00"
+func ParsePalette(s string) (Palette, error) {
+	if p, ok := Palettes[s]; ok {
+		return p, nil
+	}
+	if !strings.Contains(s, ",") {
+		return nil, fmt.Errorf("unknown palette %q, expected one of %s or a list of hex colours", s, strings.Join(PaletteNames(), ", "))
+	}
+
+	var p Palette
+	for _, hex := range strings.Split(s, ",") {
+		hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+		if len(hex) != 6 {
+			return nil, fmt.Errorf("invalid colour %q in palette, expected 6 hex digits", hex)
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid colour %q in palette: %w", hex, err)
+		}
+		p = append(p, rgb(uint32(v)))
+	}
+	if len(p) < 2 {
+		return nil, fmt.Errorf("palette needs at least 2 colours, got %d", len(p))
+	}
+	return p, nil
+}
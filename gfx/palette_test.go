@@ -0,0 +1,49 @@
+package gfx
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePalette(t *testing.T) {
+	p, err := ParsePalette("000000, #FFB000")
+	if assert.NoError(t, err) {
+		assert.Equal(t, Palette{{0, 0, 0, 255}, {0xff, 0xb0, 0, 255}}, p)
+	}
+
+	p, err = ParsePalette("amber")
+	if assert.NoError(t, err) {
+		assert.Equal(t, Palettes["amber"], p)
+	}
+
+	for _, bad := range []string{"", "000000", "000000,fff", "000000,gggggg", "purple"} {
+		_, err := ParsePalette(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestPaletteColour(t *testing.T) {
+	four := Palettes["octo"]
+	assert.Equal(t, color.RGBA{0xff, 0x66, 0x00, 255}, four.Colour(2))
+
+	// a two colour palette shows every plane as lit
+	two := Palettes["classic"]
+	assert.Equal(t, two[1], two.Colour(2))
+	assert.Equal(t, two[1], two.Colour(3))
+}
+
+func TestImageWithFourColours(t *testing.T) {
+	f := NewFramebuffer(4, 1)
+	f.Pixels = []uint8{0, 1, 2, 3}
+
+	p := Palettes["gameboy"]
+	img := f.Image(1, p)
+	for x, v := range f.Pixels {
+		assert.Equal(t, color.Color(p[v]), img.At(x, 0))
+	}
+
+	img = f.Image(1, Palettes["classic"])
+	assert.Equal(t, []uint8{0, 1, 1, 1}, img.Pix)
+}
@@ -0,0 +1,274 @@
+package gfx
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"sort"
+)
+
+// Scaler enlarges the display for the screen or a file. It's all done on the CPU so it works the same in every
+// backend and in screenshots.
+type Scaler interface {
+	// Scale returns src made scale times bigger in each direction
+	Scale(src *image.RGBA, scale int) *image.RGBA
+}
+
+// Scalers are the scalers that can be picked by name
+var Scalers = map[string]Scaler{
+	"nearest":   Nearest{},
+	"scanlines": Scanlines{},
+	"grid":      Grid{},
+	"epx":       EPX{},
+}
+
+// ScalerNames is a sorted list of the names in Scalers
+func ScalerNames() []string {
+	names := make([]string, 0, len(Scalers))
+	for name := range Scalers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseScaler looks up a scaler by name, an empty name is Nearest
+func ParseScaler(name string) (Scaler, error) {
+	if name == "" {
+		return Nearest{}, nil
+	}
+	s, ok := Scalers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scaler %q", name)
+	}
+	return s, nil
+}
+
+// RGBA is the frame as an image with one pixel per pixel, in the colours of the palette
+func (f *Framebuffer) RGBA(p Palette) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+	for i := range f.Pixels {
+		c := f.Colour(i, p)
+		copy(img.Pix[i*4:], []uint8{c.R, c.G, c.B, c.A})
+	}
+	return img
+}
+
+// Nearest makes every pixel a square, the display as it is just bigger
+type Nearest struct{}
+
+func (Nearest) Scale(src *image.RGBA, scale int) *image.RGBA {
+	b := src.Bounds()
+	return resize(src, b.Dx()*scale, b.Dy()*scale)
+}
+
+// Scanlines darkens the bottom of every row of pixels like the gaps between the lines of a CRT
+type Scanlines struct{}
+
+func (Scanlines) Scale(src *image.RGBA, scale int) *image.RGBA {
+	dst := Nearest{}.Scale(src, scale)
+	if scale < 2 {
+		return dst
+	}
+
+	gap := scale / 3
+	if gap < 1 {
+		gap = 1
+	}
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		if y%scale >= scale-gap {
+			dim(dst.Pix[y*dst.Stride : (y+1)*dst.Stride])
+		}
+	}
+	return dst
+}
+
+// Grid darkens the edges of every pixel so each one can be picked out like on an LCD
+type Grid struct{}
+
+func (Grid) Scale(src *image.RGBA, scale int) *image.RGBA {
+	dst := Nearest{}.Scale(src, scale)
+	if scale < 3 {
+		return dst
+	}
+
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		row := dst.Pix[y*dst.Stride : (y+1)*dst.Stride]
+		if y%scale == scale-1 {
+			dim(row)
+			continue
+		}
+		for x := scale - 1; x < dst.Rect.Dx(); x += scale {
+			dim(row[x*4 : x*4+4])
+		}
+	}
+	return dst
+}
+
+// EPX smooths the diagonal edges of sprites with the EPX (or Scale2x) algorithm. Each pass doubles the size, so it's
+// only used for the even part of the scale, whatever odd factor is left is made up with Nearest. That keeps every
+// pixel the same size, an odd scale like 3 isn't smoothed at all.
+type EPX struct{}
+
+func (EPX) Scale(src *image.RGBA, scale int) *image.RGBA {
+	img := src
+	for scale > 1 && scale%2 == 0 {
+		img, scale = epx(img), scale/2
+	}
+	return Nearest{}.Scale(img, scale)
+}
+
+// epx doubles the size of src, each pixel P becomes four, 1 to 4, using its neighbours A to D
+//
+//	  A         1 2
+//	C P B  ->   3 4
+//	  D
+func epx(src *image.RGBA) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w*2, h*2))
+
+	// the pixel at x, y, or p for anything off the edge
+	at := func(x, y int, p color.RGBA) color.RGBA {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return p
+		}
+		return src.RGBAAt(x, y)
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := src.RGBAAt(x, y)
+			a, b, c, d := at(x, y-1, p), at(x+1, y, p), at(x-1, y, p), at(x, y+1, p)
+
+			p1, p2, p3, p4 := p, p, p, p
+			if c == a && c != d && a != b {
+				p1 = a
+			}
+			if a == b && a != c && b != d {
+				p2 = b
+			}
+			if d == c && d != b && c != a {
+				p3 = c
+			}
+			if b == d && b != a && d != c {
+				p4 = d
+			}
+
+			dst.SetRGBA(x*2, y*2, p1)
+			dst.SetRGBA(x*2+1, y*2, p2)
+			dst.SetRGBA(x*2, y*2+1, p3)
+			dst.SetRGBA(x*2+1, y*2+1, p4)
+		}
+	}
+	return dst
+}
+
+// resize scales src to w x h by repeating pixels
+func resize(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		srow := src.Pix[(y*sh/h)*src.Stride:]
+		drow := dst.Pix[y*dst.Stride:]
+		for x := 0; x < w; x++ {
+			sx := x * sw / w
+			copy(drow[x*4:x*4+4], srow[sx*4:sx*4+4])
+		}
+	}
+	return dst
+}
+
+// dim halves the brightness of RGBA pixels
+func dim(pix []uint8) {
+	for i := 0; i < len(pix); i += 4 {
+		pix[i] /= 2
+		pix[i+1] /= 2
+		pix[i+2] /= 2
+	}
+}
+
+// Render draws the frame scale times bigger with a scaler, or Nearest if it's nil
+func Render(f *Framebuffer, scale int, p Palette, s Scaler) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+	if s == nil {
+		s = Nearest{}
+	}
+	if _, ok := s.(Nearest); ok && f.Intensity == nil {
+		// every colour is in the palette, there's no need to go through RGBA
+		return f.Image(scale, p)
+	}
+	return s.Scale(f.RGBA(p), scale)
+}
+
+// paletted converts an image for a GIF. The colours are kept exactly if there are few enough of them, which there
+// usually are as scalers only add a darker shade of the palette.
+func paletted(img image.Image) *image.Paletted {
+	if p, ok := img.(*image.Paletted); ok {
+		return p
+	}
+
+	b := img.Bounds()
+	var colours color.Palette
+	seen := map[color.Color]bool{}
+	for y := b.Min.Y; y < b.Max.Y && len(colours) <= 256; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.At(x, y)
+			if !seen[c] {
+				seen[c] = true
+				colours = append(colours, c)
+			}
+		}
+	}
+
+	if len(colours) > 256 {
+		dst := image.NewPaletted(b, palette.Plan9)
+		draw.FloydSteinberg.Draw(dst, b, img, b.Min)
+		return dst
+	}
+
+	dst := image.NewPaletted(b, colours)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+	return dst
+}
+
+// Fit is the biggest rectangle with the aspect ratio of a w x h display that fits in the middle of an area, with bars
+// either side or above and below to fill the rest
+func Fit(w, h int, area image.Rectangle) image.Rectangle {
+	aw, ah := area.Dx(), area.Dy()
+	if w <= 0 || h <= 0 {
+		return area
+	}
+
+	if w*ah > h*aw {
+		// wider than the area, it fills the width
+		fh := h * aw / w
+		y := area.Min.Y + (ah-fh)/2
+		return image.Rect(area.Min.X, y, area.Max.X, y+fh)
+	}
+	fw := w * ah / h
+	x := area.Min.X + (aw-fw)/2
+	return image.Rect(x, area.Min.Y, x+fw, area.Max.Y)
+}
+
+// FitInteger is like Fit but only scales by whole numbers, so every pixel is the same size. If the area is too small
+// for even one screen pixel per pixel it's the same as Fit.
+func FitInteger(w, h int, area image.Rectangle) image.Rectangle {
+	if w <= 0 || h <= 0 {
+		return area
+	}
+
+	scale := area.Dx() / w
+	if s := area.Dy() / h; s < scale {
+		scale = s
+	}
+	if scale < 1 {
+		return Fit(w, h, area)
+	}
+
+	r := image.Rect(0, 0, w*scale, h*scale)
+	return r.Add(area.Min).Add(image.Pt((area.Dx()-r.Dx())/2, (area.Dy()-r.Dy())/2))
+}
@@ -0,0 +1,166 @@
+package gfx
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testOff = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	testOn  = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	testDim = color.RGBA{0x7f, 0x7f, 0x7f, 0xff}
+)
+
+// pattern draws an image from rows of '#' and '.', each row must be the same length
+func pattern(rows ...string) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			img.SetRGBA(x, y, testOff)
+			if c == '#' {
+				img.SetRGBA(x, y, testOn)
+			}
+		}
+	}
+	return img
+}
+
+// picture is the reverse of pattern, with '+' for dimmed pixels
+func picture(img *image.RGBA) string {
+	b := &strings.Builder{}
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			switch img.RGBAAt(x, y) {
+			case testOn:
+				b.WriteByte('#')
+			case testDim:
+				b.WriteByte('+')
+			default:
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func TestNearest(t *testing.T) {
+	img := Nearest{}.Scale(pattern("#.", ".#"), 2)
+	assert.Equal(t, "##..\n##..\n..##\n..##\n", picture(img))
+}
+
+func TestScanlines(t *testing.T) {
+	img := Scanlines{}.Scale(pattern("#."), 3)
+	assert.Equal(t, "###...\n###...\n+++...\n", picture(img))
+
+	// too small to leave a gap
+	img = Scanlines{}.Scale(pattern("#."), 1)
+	assert.Equal(t, "#.\n", picture(img))
+}
+
+func TestGrid(t *testing.T) {
+	img := Grid{}.Scale(pattern("##"), 3)
+	assert.Equal(t, "##+##+\n##+##+\n++++++\n", picture(img))
+}
+
+func TestEPX(t *testing.T) {
+	// the steps of a diagonal line are filled in
+	img := EPX{}.Scale(pattern(
+		".#..",
+		"..#.",
+	), 2)
+	assert.Equal(t, ""+
+		"..##....\n"+
+		"..###...\n"+
+		"...###..\n"+
+		"....##..\n", picture(img))
+
+	// the corners of a square are rounded off
+	img = EPX{}.Scale(pattern(
+		"....",
+		".##.",
+		".##.",
+		"....",
+	), 2)
+	assert.Equal(t, ""+
+		"........\n"+
+		"........\n"+
+		"...##...\n"+
+		"..####..\n"+
+		"..####..\n"+
+		"...##...\n"+
+		"........\n"+
+		"........\n", picture(img))
+
+	// any scale works, not just powers of two
+	for _, scale := range []int{1, 3, 4, 5, 10} {
+		img = EPX{}.Scale(pattern("#.", ".#"), scale)
+		assert.Equal(t, image.Rect(0, 0, 2*scale, 2*scale), img.Rect, "scale %d", scale)
+	}
+}
+
+func TestEPXOddScale(t *testing.T) {
+	src := pattern(
+		".#..",
+		"..#.",
+	)
+
+	// an odd scale can't be split into EPX passes, every pixel becomes an even 3x3 block
+	img := EPX{}.Scale(src, 3)
+	require.Equal(t, image.Rect(0, 0, 12, 6), img.Rect)
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			assert.Equal(t, src.RGBAAt(x/3, y/3), img.RGBAAt(x, y), "pixel %d,%d", x, y)
+		}
+	}
+
+	// what's left over after the EPX passes is made up the same way
+	assert.Equal(t, Nearest{}.Scale(EPX{}.Scale(src, 2), 3), EPX{}.Scale(src, 6))
+	assert.Equal(t, Nearest{}.Scale(EPX{}.Scale(src, 4), 5), EPX{}.Scale(src, 20))
+}
+
+func TestRender(t *testing.T) {
+	f := NewFramebuffer(2, 1)
+	f.Set(0, 0, true)
+	p := Palette{testOff, testOn}
+
+	// nearest stays paletted
+	_, ok := Render(f, 2, p, nil).(*image.Paletted)
+	assert.True(t, ok)
+
+	img, ok := Render(f, 3, p, Scanlines{}).(*image.RGBA)
+	require.True(t, ok)
+	assert.Equal(t, "###...\n###...\n+++...\n", picture(img))
+}
+
+func TestPaletted(t *testing.T) {
+	img := paletted(Scanlines{}.Scale(pattern("#."), 3))
+	assert.Len(t, img.Palette, 3)
+	assert.Equal(t, testDim, color.RGBAModel.Convert(img.At(0, 2)))
+	assert.Equal(t, testOn, color.RGBAModel.Convert(img.At(0, 0)))
+}
+
+func TestFit(t *testing.T) {
+	// the same shape fills it
+	assert.Equal(t, image.Rect(0, 0, 640, 320), Fit(64, 32, image.Rect(0, 0, 640, 320)))
+	// taller, bars above and below
+	assert.Equal(t, image.Rect(0, 160, 640, 480), Fit(64, 32, image.Rect(0, 0, 640, 640)))
+	// wider, bars at the sides
+	assert.Equal(t, image.Rect(100, 0, 740, 320), Fit(64, 32, image.Rect(0, 0, 840, 320)))
+	assert.Equal(t, image.Rect(0, 0, 0, 0), Fit(64, 32, image.Rect(0, 0, 0, 0)))
+}
+
+func TestFitInteger(t *testing.T) {
+	assert.Equal(t, image.Rect(0, 0, 640, 320), FitInteger(64, 32, image.Rect(0, 0, 640, 320)))
+	// 15 times across and 16 down would fit, so it's 15 with bars all round
+	assert.Equal(t, image.Rect(20, 20, 980, 500), FitInteger(64, 32, image.Rect(0, 0, 1000, 520)))
+	// the high resolution display in the same window is half the scale
+	assert.Equal(t, image.Rect(0, 0, 640, 320), FitInteger(128, 64, image.Rect(0, 0, 640, 320)))
+	// too small for whole pixels
+	assert.Equal(t, image.Rect(0, 8, 32, 24), FitInteger(64, 32, image.Rect(0, 0, 32, 32)))
+}
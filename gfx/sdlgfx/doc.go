@@ -0,0 +1,3 @@
+// Package sdlgfx draws the display in an SDL2 window. It needs cgo and the SDL2 development libraries, without them (or
+// when built with the nosdl tag) the package is empty.
+package sdlgfx
@@ -0,0 +1,235 @@
+//go:build cgo && !nosdl
+
+package sdlgfx
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/cuotos/chip8/gfx"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+type Graphics struct {
+	Renderer *sdl.Renderer
+	Texture  *sdl.Texture
+	Palette  gfx.Palette
+	Scaler   gfx.Scaler         // how the display is enlarged, nil leaves it to the renderer like Nearest
+	Title    string             // shown in the title bar along with the frame rate, see UpdateTitle
+	HUD      *gfx.HUD           // drawn over the display when it's visible, its FPS is kept up to date
+	Keypad   *gfx.VirtualKeypad // drawn beside or below the display when it's visible, see KeyAt
+
+	window              *sdl.Window
+	texWidth, texHeight int
+	last                gfx.Framebuffer // the frame on screen, to redraw it
+	keypadTexture       *sdl.Texture
+	keypadSize          int
+	keypadArea          image.Rectangle // where the keypad was last drawn, in renderer pixels
+
+	presented int // frames presented since the title was last updated
+	titleAt   time.Time
+}
+
+// New opens a resizable window for a width x height display with each pixel scale x scale
+func New(width, height, scale int) (*Graphics, error) {
+	g := &Graphics{
+		Palette: gfx.DefaultPalette,
+	}
+
+	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
+		return g, err
+	}
+
+	w, err := sdl.CreateWindow("chip8", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, int32(width*scale), int32(height*scale), sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE)
+	if err != nil {
+		return nil, err
+	}
+	g.window = w
+
+	r, err := sdl.CreateRenderer(w, -1, sdl.RENDERER_ACCELERATED)
+	if err != nil {
+		return nil, err
+	}
+	g.Renderer = r
+
+	t, err := r.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(width), int32(height))
+	if err != nil {
+		return nil, err
+	}
+	g.Texture = t
+	g.texWidth, g.texHeight = width, height
+
+	return g, nil
+}
+
+func (s *Graphics) Present(frame *gfx.Framebuffer) {
+	s.last.CopyFrom(frame)
+	s.presented++
+	s.draw()
+}
+
+// Redraw presents the last frame again, for when the window has been resized or uncovered
+func (s *Graphics) Redraw() {
+	s.draw()
+}
+
+// ToggleFullscreen switches between a window and the whole of the desktop
+func (s *Graphics) ToggleFullscreen() {
+	var flags uint32
+	if s.window.GetFlags()&sdl.WINDOW_FULLSCREEN_DESKTOP != sdl.WINDOW_FULLSCREEN_DESKTOP {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	s.window.SetFullscreen(flags)
+}
+
+// UpdateTitle shows the Title and how many frames a second are being presented in the title bar. It can be called
+// as often as needed, it's only changed once a second.
+func (s *Graphics) UpdateTitle(now time.Time) {
+	if s.titleAt.IsZero() {
+		s.titleAt = now
+		s.window.SetTitle(s.Title)
+		return
+	}
+	elapsed := now.Sub(s.titleAt)
+	if elapsed < time.Second {
+		return
+	}
+
+	fps := float64(s.presented) / elapsed.Seconds()
+	s.window.SetTitle(fmt.Sprintf("%s - %.0f fps", s.Title, fps))
+	if s.HUD != nil {
+		s.HUD.FPS = fps
+	}
+	s.presented = 0
+	s.titleAt = now
+}
+
+// draw fits the last frame in the middle of the window at the biggest whole scale, keeping every pixel the same size,
+// with black bars around it. Nearest is left to the renderer, any other scaler enlarges it on the CPU. A change of
+// resolution, eg to the 128x64 SUPER-CHIP display, just halves the scale. The HUD needs the full size image to draw
+// over, so it's always scaled on the CPU while the HUD is showing.
+func (s *Graphics) draw() {
+	frame := &s.last
+	if frame.Width == 0 || frame.Height == 0 {
+		return
+	}
+
+	w, h, err := s.Renderer.GetOutputSize()
+	if err != nil {
+		return
+	}
+	area := image.Rect(0, 0, int(w), int(h))
+	s.keypadArea = image.Rectangle{}
+	if s.Keypad != nil && s.Keypad.Visible {
+		area, s.keypadArea = gfx.SplitKeypad(area)
+	}
+	dst := gfx.FitInteger(frame.Width, frame.Height, area)
+
+	img := frame.RGBA(s.Palette)
+	hud := s.HUD != nil && s.HUD.Visible
+	if _, nearest := s.Scaler.(gfx.Nearest); hud || s.Scaler != nil && !nearest {
+		scaler := s.Scaler
+		if scaler == nil {
+			scaler = gfx.Nearest{}
+		}
+		scale := dst.Dx() / frame.Width
+		if scale < 1 {
+			scale = 1
+		}
+		img = scaler.Scale(img, scale)
+	}
+	if hud {
+		s.HUD.Draw(img, time.Now())
+	}
+
+	// the texture is recreated whenever the size of the image changes, a new resolution or window size
+	if img.Rect.Dx() != s.texWidth || img.Rect.Dy() != s.texHeight {
+		t, err := s.Renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(img.Rect.Dx()), int32(img.Rect.Dy()))
+		if err != nil {
+			return
+		}
+		s.Texture.Destroy()
+		s.Texture = t
+		s.texWidth, s.texHeight = img.Rect.Dx(), img.Rect.Dy()
+	}
+
+	s.Renderer.SetDrawColor(0, 0, 0, 255)
+	s.Renderer.Clear()
+	s.Texture.Update(nil, img.Pix, img.Stride)
+	s.Renderer.Copy(s.Texture, nil, sdlRect(dst))
+	if !s.keypadArea.Empty() {
+		s.drawKeypad()
+	}
+	s.Renderer.Present()
+}
+
+// drawKeypad draws the keypad into its own texture, made at the size it's shown so the text is crisp
+func (s *Graphics) drawKeypad() {
+	size := s.keypadArea.Dx()
+	if size != s.keypadSize || s.keypadTexture == nil {
+		t, err := s.Renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(size), int32(size))
+		if err != nil {
+			return
+		}
+		if s.keypadTexture != nil {
+			s.keypadTexture.Destroy()
+		}
+		s.keypadTexture = t
+		s.keypadSize = size
+	}
+
+	img := s.Keypad.Image(size)
+	s.keypadTexture.Update(nil, img.Pix, img.Stride)
+	s.Renderer.Copy(s.keypadTexture, nil, sdlRect(s.keypadArea))
+}
+
+// KeyAt is the key of the keypad at a point in the window, as given by mouse events, false if there isn't one
+func (s *Graphics) KeyAt(x, y int32) (uint8, bool) {
+	if s.keypadArea.Empty() {
+		return 0, false
+	}
+
+	// on high DPI screens the renderer has more pixels than the window has points
+	ww, wh := s.window.GetSize()
+	ow, oh, err := s.Renderer.GetOutputSize()
+	if err != nil || ww == 0 || wh == 0 {
+		return 0, false
+	}
+	p := image.Pt(int(x*ow/ww), int(y*oh/wh))
+
+	return gfx.KeyAt(s.keypadArea.Dx(), p.Sub(s.keypadArea.Min))
+}
+
+// ShowKeypad shows or hides the keypad, widening the window to make room for it unless it's fullscreen
+func (s *Graphics) ShowKeypad(show bool) {
+	if s.Keypad == nil || s.Keypad.Visible == show {
+		return
+	}
+	s.Keypad.Visible = show
+
+	if s.window.GetFlags()&sdl.WINDOW_FULLSCREEN_DESKTOP == 0 {
+		w, h := s.window.GetSize()
+		if show {
+			w += h
+		} else if w > 2*h {
+			w -= h
+		}
+		s.window.SetSize(w, h)
+	}
+	s.draw()
+}
+
+func sdlRect(r image.Rectangle) *sdl.Rect {
+	return &sdl.Rect{X: int32(r.Min.X), Y: int32(r.Min.Y), W: int32(r.Dx()), H: int32(r.Dy())}
+}
+
+func (s *Graphics) Cleanup() {
+	s.Texture.Destroy()
+	if s.keypadTexture != nil {
+		s.keypadTexture.Destroy()
+	}
+	s.Renderer.Destroy()
+	s.window.Destroy()
+	sdl.Quit()
+}
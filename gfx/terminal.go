@@ -1,57 +1,146 @@
 package gfx
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"os"
 )
 
 const (
-	x = 64
-	y = 32
+	enterAltScreen = "\x1b[?1049h\x1b[?25l\x1b[2J" // switch to the alternate screen, hide the cursor and clear it
+	leaveAltScreen = "\x1b[0m\x1b[?25h\x1b[?1049l" // reset the colours, show the cursor and go back to the main screen
 )
 
+// half blocks for each combination of the top and bottom pixels in a cell, bit 0 is the top pixel and bit 1 the bottom
+var halfBlocks = [4]string{" ", "▀", "▄", "█"}
+
+// Terminal draws the display in a terminal using ANSI escapes. Each character cell shows two pixels, one above the
+// other, with Unicode half blocks so 64x32 fits in 64x16 cells. Only the cells that changed since the last frame are
+// redrawn, which keeps it playable over a slow connection.
 type Terminal struct {
-	Mem []uint16
-}
+	Out io.Writer // where the screen is drawn, defaults to stdout
 
-func (t *Terminal) SetPixel(pixel, value uint16) {
-	t.Mem[pixel] = value
-}
+	// Colour draws the pixels in the colours of the Palette using 24 bit colour, otherwise the terminal's own colours
+	// are used and any pixel that isn't 0 is lit
+	Colour  bool
+	Palette Palette
 
-func (t *Terminal) GetPixel(pixel uint16) uint16 {
-	return t.Mem[pixel]
+	started bool
+	width   int
+	height  int
+	cells   []uint64 // what's on the terminal now, see cell
+	buf     bytes.Buffer
 }
 
 func NewTerminalGFX() *Terminal {
-	gfx := &Terminal{
-		Mem: make([]uint16, x*y),
+	return &Terminal{
+		Palette: DefaultPalette,
 	}
+}
 
-	return gfx
+func (t *Terminal) out() io.Writer {
+	if t.Out == nil {
+		return os.Stdout
+	}
+	return t.Out
 }
 
-func (t *Terminal) Draw() {
+func (t *Terminal) Present(frame *Framebuffer) {
+	t.buf.Reset()
 
-	for i, p := range t.Mem {
-		if (i % x) == 0 {
-			fmt.Print("\n")
-		}
+	rows := (frame.Height + 1) / 2
+	full := !t.started || frame.Width != t.width || frame.Height != t.height
+	if !t.started {
+		t.buf.WriteString(enterAltScreen)
+		t.started = true
+	}
+	if full {
+		t.width, t.height = frame.Width, frame.Height
+		t.cells = make([]uint64, frame.Width*rows)
+		t.buf.WriteString("\x1b[0m\x1b[2J")
+	}
 
-		if p == 1 {
-			fmt.Printf("%2s", "0")
-		} else {
-			fmt.Printf("%2s", " ")
+	// the cursor is wherever the last cell was written, -1 until it's been moved this frame, and the colours are set
+	// for the last cell written, which starts as something no cell can be
+	cursor := -1
+	var colours uint64 = 1 << 63
+	for row := 0; row < rows; row++ {
+		for col := 0; col < frame.Width; col++ {
+			i := row*frame.Width + col
+			cell := t.cell(frame, col, row)
+			if !full && t.cells[i] == cell {
+				continue
+			}
+			t.cells[i] = cell
+
+			if cursor != i {
+				fmt.Fprintf(&t.buf, "\x1b[%d;%dH", row+1, col+1)
+			}
+			if t.Colour {
+				if cell != colours {
+					t.writeColours(cell)
+					colours = cell
+				}
+				t.buf.WriteString(halfBlocks[1])
+			} else {
+				t.buf.WriteString(halfBlocks[cell])
+			}
+			cursor = i + 1
+			if col == frame.Width-1 {
+				cursor = -1
+			}
 		}
 	}
 
-	fmt.Printf("\n")
+	if t.buf.Len() > 0 {
+		t.out().Write(t.buf.Bytes())
+	}
 }
 
-func (t *Terminal) Clear() {
-	for _, i := range t.Mem {
-		t.Mem[i] = 0
+// cell is what's shown in the character cell at col, row. In colour it's the RGB of the top pixel in the low 32 bits
+// and the bottom pixel in the high 32 bits, otherwise it's the index of its half block.
+func (t *Terminal) cell(frame *Framebuffer, col, row int) uint64 {
+	top := row*2*frame.Width + col
+	bottom := top + frame.Width
+	hasBottom := row*2+1 < frame.Height
+
+	if t.Colour {
+		c := frame.Colour(top, t.Palette)
+		cell := uint64(c.R) | uint64(c.G)<<8 | uint64(c.B)<<16
+		c = t.Palette[0]
+		if hasBottom {
+			c = frame.Colour(bottom, t.Palette)
+		}
+		return cell | (uint64(c.R)|uint64(c.G)<<8|uint64(c.B)<<16)<<32
+	}
+
+	// a fading pixel is lit until it's half faded
+	lit := func(i int) bool {
+		return frame.Pixels[i] != 0 && (frame.Intensity == nil || frame.Intensity[i] >= 128)
+	}
+	var block uint64
+	if lit(top) {
+		block |= 1
 	}
+	if hasBottom && lit(bottom) {
+		block |= 2
+	}
+	return block
+}
+
+// writeColours sets the colours for a cell. In colour the upper half block is always used, so the foreground colour is
+// the top pixel and the background the bottom one.
+func (t *Terminal) writeColours(cell uint64) {
+	fmt.Fprintf(&t.buf, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm", uint8(cell), uint8(cell>>8), uint8(cell>>16), uint8(cell>>32), uint8(cell>>40), uint8(cell>>48))
 }
 
-func (t *Terminal) Initialise() (func(), error) {
-	return func() {}, nil
+// Close puts the terminal back the way it was, if anything has been drawn
+func (t *Terminal) Close() error {
+	if !t.started {
+		return nil
+	}
+	t.started = false
+	_, err := io.WriteString(t.out(), leaveAltScreen)
+	return err
 }
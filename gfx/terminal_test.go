@@ -0,0 +1,164 @@
+package gfx
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var csi = regexp.MustCompile(`^\x1b\[([0-9;?]*)([a-zA-Z])`)
+
+// termScreen plays back what was written to the terminal into a grid of characters, following the cursor moves and
+// ignoring everything else
+type termScreen struct {
+	cells    [][]rune
+	row, col int
+}
+
+func newTermScreen(width, height int) *termScreen {
+	s := &termScreen{}
+	for r := 0; r < height; r++ {
+		s.cells = append(s.cells, []rune(strings.Repeat(" ", width)))
+	}
+	return s
+}
+
+func (s *termScreen) write(out string) {
+	for len(out) > 0 {
+		if m := csi.FindStringSubmatch(out); m != nil {
+			if m[2] == "H" {
+				pos := strings.Split(m[1], ";")
+				s.row, _ = strconv.Atoi(pos[0])
+				s.col, _ = strconv.Atoi(pos[1])
+				s.row--
+				s.col--
+			}
+			out = out[len(m[0]):]
+			continue
+		}
+
+		r := []rune(out)[0]
+		s.cells[s.row][s.col] = r
+		s.col++
+		out = out[len(string(r)):]
+	}
+}
+
+func (s *termScreen) String() string {
+	var lines []string
+	for _, row := range s.cells {
+		lines = append(lines, string(row))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestTerminalPresent(t *testing.T) {
+	out := &bytes.Buffer{}
+	term := NewTerminalGFX()
+	term.Out = out
+	screen := newTermScreen(64, 16)
+
+	f := NewFramebuffer(Width, Height)
+	f.Set(0, 0, true)
+	f.Set(2, 1, true)
+	f.Set(3, 0, true)
+	f.Set(3, 1, true)
+	f.Set(63, 31, true)
+	term.Present(f)
+
+	assert.True(t, strings.HasPrefix(out.String(), enterAltScreen))
+	screen.write(out.String())
+
+	lines := strings.Split(screen.String(), "\n")
+	if assert.Len(t, lines, 16) {
+		assert.Equal(t, "▀ ▄█"+strings.Repeat(" ", 60), lines[0])
+		assert.Equal(t, strings.Repeat(" ", 64), lines[1])
+		assert.Equal(t, strings.Repeat(" ", 63)+"▄", lines[15])
+	}
+
+	// only the cell that changed is drawn the next time
+	out.Reset()
+	f.Set(0, 0, false)
+	f.Set(10, 5, true)
+	term.Present(f)
+	assert.Equal(t, "\x1b[1;1H \x1b[3;11H▄", out.String())
+
+	screen.write(out.String())
+	lines = strings.Split(screen.String(), "\n")
+	assert.Equal(t, "  ▄█"+strings.Repeat(" ", 60), lines[0])
+	assert.Equal(t, strings.Repeat(" ", 10)+"▄"+strings.Repeat(" ", 53), lines[2])
+
+	// and nothing at all when nothing changed
+	out.Reset()
+	term.Present(f)
+	assert.Empty(t, out.String())
+
+	assert.NoError(t, term.Close())
+	assert.Equal(t, leaveAltScreen, out.String())
+}
+
+func TestTerminalRedrawsOnResize(t *testing.T) {
+	out := &bytes.Buffer{}
+	term := NewTerminalGFX()
+	term.Out = out
+
+	term.Present(NewFramebuffer(Width, Height))
+	out.Reset()
+
+	term.Present(NewFramebuffer(128, 64))
+	assert.Equal(t, 128*32, strings.Count(out.String(), " "))
+}
+
+func TestTerminalColour(t *testing.T) {
+	out := &bytes.Buffer{}
+	term := NewTerminalGFX()
+	term.Out = out
+	term.Colour = true
+	term.Palette = Palette{{16, 16, 16, 255}, {255, 176, 0, 255}, {0, 0, 255, 255}}
+
+	f := NewFramebuffer(Width, Height)
+	term.Present(f)
+	out.Reset()
+
+	f.Set(5, 0, true)
+	f.Set(6, 0, true)
+	f.Set(7, 1, true)
+	term.Present(f)
+
+	// the top pixel is the foreground of an upper half block and the bottom is the background, the colours are only
+	// set again when they change
+	assert.Equal(t, "\x1b[1;6H"+
+		"\x1b[38;2;255;176;0m\x1b[48;2;16;16;16m▀▀"+
+		"\x1b[38;2;16;16;16m\x1b[48;2;255;176;0m▀", out.String())
+
+	// each plane of a multi-plane display has its own colour
+	out.Reset()
+	f.Pixels[2*f.Width+5] = 2
+	f.Pixels[3*f.Width+5] = 3
+	term.Present(f)
+	assert.Equal(t, "\x1b[2;6H\x1b[38;2;0;0;255m\x1b[48;2;0;0;255m▀", out.String())
+}
+
+func TestTerminalFading(t *testing.T) {
+	out := &bytes.Buffer{}
+	term := NewTerminalGFX()
+	term.Out = out
+
+	f := NewFramebuffer(2, 2)
+	f.Pixels = []uint8{1, 1, 0, 0}
+	f.Intensity = []uint8{200, 100, 0, 0}
+	term.Present(f)
+
+	// without colour a pixel is shown until it's half faded
+	assert.True(t, strings.HasSuffix(out.String(), "\x1b[1;1H▀ "))
+
+	out.Reset()
+	term.Colour = true
+	term.Palette = Palette{{0, 0, 0, 255}, {255, 255, 255, 255}}
+	term.Present(f)
+	assert.Contains(t, out.String(), "\x1b[38;2;200;200;200m\x1b[48;2;0;0;0m▀\x1b[38;2;100;100;100m")
+}
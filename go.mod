@@ -1,9 +1,15 @@
 module github.com/cuotos/chip8
 
-go 1.14
+go 1.18
 
 require (
 	github.com/hashicorp/logutils v1.0.0
 	github.com/stretchr/testify v1.6.1
 	github.com/veandco/go-sdl2 v0.4.4
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
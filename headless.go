@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/cuotos/chip8/chip"
+	"github.com/cuotos/chip8/gfx"
+)
+
+// runHeadless is the headless subcommand: chip8 headless [-frames N] [-png file] [-record file] rom
+// It runs the ROM without a window until it halts, then prints the screen, and saves it as a PNG if asked. Every frame
+// can also be recorded to an animated GIF. Time spent waiting on the delay timer is skipped, so it runs as fast as the
+// ROM allows.
+func runHeadless(args []string) int {
+	fs := flag.NewFlagSet("headless", flag.ExitOnError)
+	frames := fs.Int("frames", 600, "stop after this many frames if the ROM hasn't halted")
+	pngPath := fs.String("png", "", "save the final screen to this PNG file")
+	recordPath := fs.String("record", "", "record every frame to this GIF file")
+	scale := fs.Int("scale", 10, "size of each pixel in the PNG or GIF")
+	scalerName := fs.String("scaler", "nearest", scalerHelp())
+	palette := fs.String("palette", "", paletteHelp())
+	vblank := fs.Bool("vblank", false, vblankHelp)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("usage: chip8 headless [-frames N] [-png file] [-record file] [-scale N] [-scaler name] [-palette colours] [-vblank] <rom>")
+		return 2
+	}
+
+	// the PNG and GIF are the display as it is, so any filter in the profile is left out
+	settings, err := loadSettings(fs, settingsFlags{Palette: palette, Vblank: vblank}, fs.Arg(0))
+	if err != nil {
+		log.Print("[ERROR] ", err)
+		return 2
+	}
+
+	scaler, err := gfx.ParseScaler(*scalerName)
+	if err != nil {
+		log.Print("[ERROR] ", err)
+		return 2
+	}
+
+	c := chip.NewDefaultChip()
+	c.Initialise()
+	c.Quirks.DisplayWait = settings.DisplayWait
+	if err := c.Load(fs.Arg(0)); err != nil {
+		log.Print("[ERROR] ", err)
+		return 2
+	}
+
+	var recorder *gfx.GIFRecorder
+	if *recordPath != "" {
+		recorder = gfx.NewGIFRecorder(*scale, settings.Palette)
+		recorder.Scaler = scaler
+	}
+
+	status := 0
+	frame := 0
+frames:
+	for ; frame < *frames && c.Idle != chip.IdleHalted; frame++ {
+		if c.Idle == chip.IdleWaitKey {
+			log.Printf("[INFO] waiting for a key at %03x, there's no keyboard when headless", c.PC)
+			status = 1
+			break
+		}
+
+		// while waiting on the delay timer nothing changes until the next tick
+		for i := 0; i < chip.CyclesPerFrame && c.Idle == chip.NotIdle; i++ {
+			if err := c.EmulateCycle(); err != nil {
+				log.Print("[ERROR] ", err)
+				status = 1
+				break frames
+			}
+		}
+		c.TickTimers()
+
+		if recorder != nil {
+			recorder.AddFrame(c.Display)
+		}
+	}
+
+	if c.Idle == chip.IdleHalted {
+		log.Printf("[INFO] halted at %03x after %d frames, %d cycles", c.PC, frame, c.Cycles)
+	} else if frame == *frames {
+		log.Printf("[INFO] still running after %d frames", frame)
+	}
+
+	fmt.Print(c.Display)
+
+	if *pngPath != "" {
+		if err := gfx.SavePNG(*pngPath, c.Display, *scale, settings.Palette, scaler); err != nil {
+			log.Print("[ERROR] ", err)
+			return 1
+		}
+	}
+
+	if recorder != nil {
+		if err := recorder.Save(*recordPath); err != nil {
+			log.Print("[ERROR] ", err)
+			return 1
+		}
+		log.Printf("[INFO] recorded %d frames to %s", recorder.Frames(), *recordPath)
+	}
+
+	return status
+}
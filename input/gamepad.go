@@ -0,0 +1,148 @@
+package input
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GamepadButtons are the buttons that can be mapped, by their names in SDL's game controller database
+var GamepadButtons = []string{
+	"dpup", "dpdown", "dpleft", "dpright",
+	"a", "b", "x", "y",
+	"leftshoulder", "rightshoulder",
+	"back", "start",
+}
+
+// GamepadMap maps gamepad buttons, by name, to keys of the hex keypad
+type GamepadMap map[string]uint8
+
+// DefaultGamepadMap puts 2, 4, 6 and 8 on the D-pad, the usual directions for CHIP-8 games, and 5, the usual fire
+// button, on A
+var DefaultGamepadMap = GamepadMap{
+	"dpup": 0x2, "dpdown": 0x8, "dpleft": 0x4, "dpright": 0x6,
+	"a": 0x5, "b": 0x0, "x": 0x7, "y": 0x9,
+	"leftshoulder": 0x1, "rightshoulder": 0x3,
+	"back": 0xe, "start": 0xf,
+}
+
+// ParseGamepadMap reads a mapping of buttons to hex keys, eg "dpup=1,dpdown=4,a=5". Buttons that aren't mentioned keep
+// their key from DefaultGamepadMap and a button can be unmapped with "-", eg "b=-".
+func ParseGamepadMap(s string) (GamepadMap, error) {
+	m := GamepadMap{}
+	for button, key := range DefaultGamepadMap {
+		m[button] = key
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		button, key, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("gamepad mapping %q should be button=key", pair)
+		}
+		button = strings.ToLower(strings.TrimSpace(button))
+		if _, known := DefaultGamepadMap[button]; !known {
+			return nil, fmt.Errorf("unknown gamepad button %q, it should be one of %s", button, strings.Join(GamepadButtons, ", "))
+		}
+
+		key = strings.TrimSpace(key)
+		if key == "-" {
+			delete(m, button)
+			continue
+		}
+		k, err := strconv.ParseUint(key, 16, 8)
+		if err != nil || k > 0xf {
+			return nil, fmt.Errorf("gamepad key %q should be a hex key, 0 to f", key)
+		}
+		m[button] = uint8(k)
+	}
+
+	return m, nil
+}
+
+// String is the mapping in the form ParseGamepadMap reads, every button in the order of GamepadButtons
+func (m GamepadMap) String() string {
+	var pairs []string
+	for _, button := range GamepadButtons {
+		if key, ok := m[button]; ok {
+			pairs = append(pairs, fmt.Sprintf("%s=%x", button, key))
+		} else {
+			pairs = append(pairs, button+"=-")
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Gamepads presses keys for the buttons held on any number of gamepads. A key stays down while any button mapped to
+// it is held, on any gamepad, so two players can share a key without letting go of it for each other.
+type Gamepads struct {
+	Map GamepadMap
+
+	held map[int]map[string]bool // the buttons held on each gamepad, by its id
+}
+
+func NewGamepads(m GamepadMap) *Gamepads {
+	return &Gamepads{
+		Map:  m,
+		held: map[int]map[string]bool{},
+	}
+}
+
+// Button presses or releases a button on a gamepad, buttons that aren't mapped are ignored
+func (g *Gamepads) Button(id int, button string, pressed bool, k Keypad) {
+	key, ok := g.Map[button]
+	if !ok {
+		return
+	}
+
+	buttons := g.held[id]
+	if buttons == nil {
+		buttons = map[string]bool{}
+		g.held[id] = buttons
+	}
+	if buttons[button] == pressed {
+		return
+	}
+
+	wasDown := g.down(key)
+	if pressed {
+		buttons[button] = true
+	} else {
+		delete(buttons, button)
+	}
+	if isDown := g.down(key); isDown != wasDown {
+		k.SetKey(key, isDown)
+	}
+}
+
+// Remove forgets a gamepad that's been unplugged, releasing anything it was holding down
+func (g *Gamepads) Remove(id int, k Keypad) {
+	buttons := g.held[id]
+	names := make([]string, 0, len(buttons))
+	for button := range buttons {
+		names = append(names, button)
+	}
+	sort.Strings(names)
+
+	for _, button := range names {
+		g.Button(id, button, false, k)
+	}
+	delete(g.held, id)
+}
+
+// down is whether any button mapped to key is held on any gamepad
+func (g *Gamepads) down(key uint8) bool {
+	for _, buttons := range g.held {
+		for button := range buttons {
+			if g.Map[button] == key {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package input
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGamepadMap(t *testing.T) {
+	m, err := ParseGamepadMap("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultGamepadMap, m)
+
+	m, err = ParseGamepadMap("dpup=1, DPDown=4,b=-,start=C")
+	require.NoError(t, err)
+	assert.Equal(t, uint8(0x1), m["dpup"])
+	assert.Equal(t, uint8(0x4), m["dpdown"])
+	assert.Equal(t, uint8(0xc), m["start"])
+	assert.Equal(t, uint8(0x5), m["a"], "the rest are the defaults")
+	assert.NotContains(t, m, "b")
+
+	// the defaults aren't changed
+	assert.Equal(t, uint8(0x2), DefaultGamepadMap["dpup"])
+
+	for _, bad := range []string{"dpup", "trigger=1", "a=10", "a=g"} {
+		_, err := ParseGamepadMap(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestGamepadMapString(t *testing.T) {
+	m, err := ParseGamepadMap("dpup=1,b=-,start=c")
+	require.NoError(t, err)
+	assert.Equal(t, "dpup=1,dpdown=8,dpleft=4,dpright=6,a=5,b=-,x=7,y=9,leftshoulder=1,rightshoulder=3,back=e,start=c", m.String())
+
+	again, err := ParseGamepadMap(m.String())
+	require.NoError(t, err)
+	assert.Equal(t, m, again)
+}
+
+func TestGamepadsButton(t *testing.T) {
+	g := NewGamepads(DefaultGamepadMap)
+	pad := &recordingKeypad{}
+
+	g.Button(0, "dpup", true, pad)
+	g.Button(0, "dpup", true, pad)
+	g.Button(0, "guide", true, pad)
+	g.Button(0, "dpup", false, pad)
+	assert.Equal(t, []keyEvent{{0x2, true}, {0x2, false}}, pad.events, "repeats and unmapped buttons are ignored")
+}
+
+func TestGamepadsShareAKey(t *testing.T) {
+	m, err := ParseGamepadMap("a=5,b=5")
+	require.NoError(t, err)
+	g := NewGamepads(m)
+	pad := &recordingKeypad{}
+
+	// two buttons on one pad
+	g.Button(0, "a", true, pad)
+	g.Button(0, "b", true, pad)
+	g.Button(0, "a", false, pad)
+	assert.Equal(t, []keyEvent{{0x5, true}}, pad.events, "still held by b")
+	g.Button(0, "b", false, pad)
+	assert.Equal(t, []keyEvent{{0x5, true}, {0x5, false}}, pad.events)
+
+	// the same button on two pads
+	pad.events = nil
+	g.Button(0, "a", true, pad)
+	g.Button(1, "a", true, pad)
+	g.Button(0, "a", false, pad)
+	assert.Equal(t, []keyEvent{{0x5, true}}, pad.events)
+	g.Button(1, "a", false, pad)
+	assert.Equal(t, []keyEvent{{0x5, true}, {0x5, false}}, pad.events)
+}
+
+func TestGamepadsRemove(t *testing.T) {
+	g := NewGamepads(DefaultGamepadMap)
+	pad := &recordingKeypad{}
+
+	g.Button(3, "dpleft", true, pad)
+	g.Button(3, "a", true, pad)
+	g.Button(4, "a", true, pad)
+	pad.events = nil
+
+	// unplugging releases what it held, unless another pad is holding it too
+	g.Remove(3, pad)
+	assert.Equal(t, []keyEvent{{0x4, false}}, pad.events)
+
+	g.Remove(4, pad)
+	assert.Equal(t, []keyEvent{{0x4, false}, {0x5, false}}, pad.events)
+
+	// removing one that was never seen does nothing
+	g.Remove(9, pad)
+	assert.Len(t, pad.events, 2)
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package input
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// MakeRaw isn't supported here, terminal input needs linux or macOS
+func MakeRaw(fd uintptr) (func() error, error) {
+	return nil, fmt.Errorf("raw terminal input is not supported on %s", runtime.GOOS)
+}
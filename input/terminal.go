@@ -0,0 +1,112 @@
+package input
+
+import (
+	"io"
+	"time"
+	"unicode"
+)
+
+// DefaultReleaseAfter is how long a key is held after the terminal last sent it. It needs to be longer than the gap
+// between key repeats or a held key flickers.
+const DefaultReleaseAfter = 200 * time.Millisecond
+
+const (
+	ctrlC  = 0x03
+	escape = 0x1b
+)
+
+// TerminalKeys reads key presses from a terminal in raw mode, see MakeRaw. Terminals only send a key when it's pressed
+// (and again as it repeats), never when it's released, so a key is released once it hasn't been seen for ReleaseAfter.
+type TerminalKeys struct {
+	Keymap       map[rune]uint8
+	ReleaseAfter time.Duration
+
+	in      chan []byte
+	pressed [16]time.Time // when each key was last seen, zero if it's up
+	quit    bool
+}
+
+// NewTerminalKeys starts reading from r in the background, r is usually os.Stdin
+func NewTerminalKeys(r io.Reader) *TerminalKeys {
+	t := &TerminalKeys{
+		Keymap:       map[rune]uint8{},
+		ReleaseAfter: DefaultReleaseAfter,
+		in:           make(chan []byte, 64),
+	}
+	// a copy, so changing the keymap of one terminal doesn't change the default for the rest
+	for r, key := range DefaultKeymap {
+		t.Keymap[r] = key
+	}
+
+	go func() {
+		for {
+			buf := make([]byte, 32)
+			n, err := r.Read(buf)
+			if n > 0 {
+				t.in <- buf[:n]
+			}
+			if err != nil {
+				close(t.in)
+				return
+			}
+		}
+	}()
+
+	return t
+}
+
+// Update presses and releases keys on the keypad from everything read since the last update, without blocking. It
+// returns false once ctrl-c has been pressed or the terminal has closed.
+func (t *TerminalKeys) Update(now time.Time, k Keypad) bool {
+	for {
+		select {
+		case b, ok := <-t.in:
+			if !ok {
+				t.quit = true
+				t.release(time.Time{}, k)
+				return false
+			}
+			t.feed(b, now, k)
+
+		default:
+			t.release(now, k)
+			return !t.quit
+		}
+	}
+}
+
+// feed handles one read from the terminal
+func (t *TerminalKeys) feed(b []byte, now time.Time, k Keypad) {
+	for _, r := range string(b) {
+		switch r {
+		case ctrlC:
+			t.quit = true
+			return
+		case escape:
+			// the rest is an escape sequence, from the arrow keys or similar, none of which are mapped
+			return
+		}
+
+		key, ok := t.Keymap[unicode.ToLower(r)]
+		if !ok {
+			continue
+		}
+		if t.pressed[key].IsZero() {
+			k.SetKey(key, true)
+		}
+		t.pressed[key] = now
+	}
+}
+
+// release lets go of every key that hasn't been seen for ReleaseAfter, or all of them if now is zero
+func (t *TerminalKeys) release(now time.Time, k Keypad) {
+	for key, seen := range t.pressed {
+		if seen.IsZero() {
+			continue
+		}
+		if now.IsZero() || now.Sub(seen) >= t.ReleaseAfter {
+			t.pressed[key] = time.Time{}
+			k.SetKey(uint8(key), false)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package input
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type keyEvent struct {
+	Key     uint8
+	Pressed bool
+}
+
+// recordingKeypad keeps every key press and release
+type recordingKeypad struct {
+	events []keyEvent
+}
+
+func (r *recordingKeypad) SetKey(key uint8, pressed bool) {
+	r.events = append(r.events, keyEvent{key, pressed})
+}
+
+func newTestTerminalKeys() *TerminalKeys {
+	return &TerminalKeys{
+		Keymap:       DefaultKeymap,
+		ReleaseAfter: 100 * time.Millisecond,
+		in:           make(chan []byte, 8),
+	}
+}
+
+func TestTerminalKeysReleaseAfterTimeout(t *testing.T) {
+	start := time.Now()
+	keys := newTestTerminalKeys()
+	pad := &recordingKeypad{}
+
+	keys.in <- []byte("w")
+	assert.True(t, keys.Update(start, pad))
+	assert.Equal(t, []keyEvent{{0x5, true}}, pad.events)
+
+	// still held
+	assert.True(t, keys.Update(start.Add(50*time.Millisecond), pad))
+	assert.Len(t, pad.events, 1)
+
+	// the key repeating keeps it held, without pressing it again
+	keys.in <- []byte("w")
+	keys.Update(start.Add(80*time.Millisecond), pad)
+	keys.Update(start.Add(150*time.Millisecond), pad)
+	assert.Len(t, pad.events, 1)
+
+	keys.Update(start.Add(180*time.Millisecond), pad)
+	assert.Equal(t, []keyEvent{{0x5, true}, {0x5, false}}, pad.events)
+}
+
+func TestTerminalKeysMapping(t *testing.T) {
+	keys := newTestTerminalKeys()
+	pad := &recordingKeypad{}
+
+	// upper case is the same key, anything not on the keypad is ignored, and so are escape sequences
+	keys.in <- []byte("1Rk")
+	keys.in <- []byte("\x1b[Av")
+	keys.Update(time.Now(), pad)
+
+	assert.Equal(t, []keyEvent{{0x1, true}, {0xd, true}}, pad.events)
+}
+
+func TestNewTerminalKeysCopiesTheKeymap(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	keys := NewTerminalKeys(r)
+	assert.Equal(t, DefaultKeymap, keys.Keymap)
+
+	// the defaults aren't changed
+	keys.Keymap['1'] = 0xf
+	assert.Equal(t, uint8(0x1), DefaultKeymap['1'])
+}
+
+func TestTerminalKeysQuit(t *testing.T) {
+	keys := newTestTerminalKeys()
+	pad := &recordingKeypad{}
+
+	keys.in <- []byte("z\x03")
+	assert.False(t, keys.Update(time.Now(), pad))
+}
+
+func TestTerminalKeysClosed(t *testing.T) {
+	r, w := io.Pipe()
+	keys := NewTerminalKeys(r)
+	pad := &recordingKeypad{}
+
+	w.Write([]byte("x"))
+	w.Close()
+
+	// once the terminal has gone every key is let go
+	deadline := time.Now().Add(time.Second)
+	for keys.Update(time.Now(), pad) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, []keyEvent{{0x0, true}, {0x0, false}}, pad.events)
+}
@@ -1,24 +1,73 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
 	"log"
-	"math/rand"
 	"os"
+	"os/signal"
 	"time"
 
 	"github.com/cuotos/chip8/chip"
 	"github.com/cuotos/chip8/gfx"
+	"github.com/cuotos/chip8/input"
 	"github.com/hashicorp/logutils"
-	"github.com/veandco/go-sdl2/sdl"
 )
 
-// for stuck check
-var prevPC uint16
+var (
+	romPath     = flag.String("rom", "roms/pong.ch8", "path to the ROM to run")
+	displayName = flag.String("display", defaultDisplay, "where to draw the screen, sdl or terminal (redirect stderr to keep the logs off the screen)")
+	colour      = flag.Bool("colour", false, "draw the terminal display in 24 bit colour")
+	paletteName = flag.String("palette", "", paletteHelp())
+	filterName  = flag.String("filter", "", filterHelp)
+	vblank      = flag.Bool("vblank", false, vblankHelp)
+	gamepadMap  = flag.String("gamepad", "", gamepadHelp)
+	scalerName  = flag.String("scaler", "nearest", scalerHelp())
+	shotScale   = flag.Int("screenshot-scale", 10, "size of each pixel in screenshots and recordings")
+	showHUD     = flag.Bool("hud", false, "show the status overlay in the SDL window, F1 toggles it")
+	showKeypad  = flag.Bool("keypad", false, "show a keypad that can be clicked beside the display in the SDL window, F2 toggles it")
+	shotHUD     = flag.Bool("screenshot-hud", false, "include the status overlay in screenshots while it's showing")
+	keyRelease  = flag.Duration("key-release", input.DefaultReleaseAfter, "how long a key is held after the terminal last sent it")
+	tracePath   = flag.String("trace", "", "write a trace of every executed instruction to this file")
+	traceFormat = flag.String("trace-format", "jsonl", "format of the trace, jsonl or binary")
+	tracePC     = flag.String("trace-pc", "", "only trace instructions in this PC range, in hex, eg 200-2ff")
+	traceCycles = flag.String("trace-cycles", "", "only trace this window of cycles, eg 1000-2000")
+)
+
+// sdlOptions are everything the SDL window is set up with, see newSDLDisplay
+type sdlOptions struct {
+	RomPath string
+	Palette gfx.Palette
+	Scaler  gfx.Scaler
+	HUD     *gfx.HUD
+	Keypad  input.Keypad // where game controller buttons and clicks on the on screen keypad are pressed
+	Gamepad input.GamepadMap
+	Hotkeys map[string]func() // by SDL key name, eg "F12"
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
+	OnScreenKeypad *gfx.VirtualKeypad
+	ShowKeypad     bool
 }
+
+// fastForwardSpeed is how many times faster than normal the ROM runs while fast forwarding
+const fastForwardSpeed = 4
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "tracediff":
+			os.Exit(runTraceDiff(os.Args[2:]))
+		case "testrom":
+			os.Exit(runTestROM(os.Args[2:]))
+		case "headless":
+			os.Exit(runHeadless(os.Args[2:]))
+		case "batch":
+			os.Exit(runBatch(os.Args[2:]))
+		}
+	}
+
+	flag.Parse()
 
 	filter := &logutils.LevelFilter{
 		Levels:   []logutils.LogLevel{"DEBUG", "INFO", "WARN", "ERROR"},
@@ -32,65 +81,234 @@ func main() {
 	// initialise the chip
 	c.Initialise()
 
-	//	gfx := gfx.NewTerminalGFX() // TODO: this will be added to the NewChip at some point along with a logger and stuff
-
-	gfx, err := gfx.NewSDLGraphics(64, 32, 10)
+	err := c.Load(*romPath)
 	if err != nil {
 		log.Fatal("[ERROR] ", err)
 	}
-	defer gfx.Cleanup()
 
-	c.GFX = gfx
+	if *tracePath != "" {
+		tracer, traceFile, err := openTrace(*tracePath, *traceFormat, *tracePC, *traceCycles)
+		if err != nil {
+			log.Fatal("[ERROR] ", err)
+		}
+		defer traceFile.Close()
+		c.Tracer = tracer
+	}
 
-	err = c.Load("roms/pong.ch8")
+	settings, err := loadSettings(flag.CommandLine, settingsFlags{
+		Palette: paletteName,
+		Filter:  filterName,
+		Gamepad: gamepadMap,
+		Vblank:  vblank,
+	}, *romPath)
+	if err != nil {
+		log.Fatal("[ERROR] ", err)
+	}
+	colours := settings.Palette
+	scaler, err := gfx.ParseScaler(*scalerName)
 	if err != nil {
 		log.Fatal("[ERROR] ", err)
 	}
+	c.Quirks.DisplayWait = settings.DisplayWait
+	runner := chip.NewRunner(c)
+	// screen is the last frame published by the runner
+	screen := c.Screen()
 
-	clock := time.NewTicker(time.Second / time.Duration(500))
-	timers := time.NewTicker(time.Second / time.Duration(60))
-	video := time.NewTicker(time.Second / time.Duration(60))
+	hud := &gfx.HUD{Visible: *showHUD}
+	keypad := &gfx.VirtualKeypad{}
+	// notify logs a message and shows it in the HUD
+	notify := func(format string, args ...interface{}) {
+		log.Printf("[INFO] "+format, args...)
+		hud.Message(time.Now(), fmt.Sprintf(format, args...))
+	}
 
-	for processEvents() {
+	// the chip runs on its own goroutine, this one handles the window and input. Errors from either end up on failed,
+	// the first is kept and any after it are only logged, so sending never blocks the runner.
+	failed := make(chan error, 1)
+	fail := func(err error) {
 		select {
-		case <-clock.C:
-			err := c.EmulateCycle()
+		case failed <- err:
+		default:
+			log.Print("[ERROR] ", err)
+		}
+	}
+	stopped := make(chan struct{})
+
+	paused := false
+	speed := 1
+	hotkeys := map[string]func(){
+		"F1": func() {
+			hud.Visible = !hud.Visible
+		},
+		"F3": func() {
+			// read it again so changes to the file are picked up, eg while writing a ROM
+			rom, err := ioutil.ReadFile(*romPath)
 			if err != nil {
-				c.DiagDump()
-				log.Fatal("[ERROR] ", err)
+				log.Print("[ERROR] reload: ", err)
+				return
 			}
-
-			//fmt.Printf("tick: oc:%04x pc:%x I:%02x, S:%x, r:%x\n", c.OpCode, c.PC-512, c.I, c.Stack, c.V)
-
-		case <-video.C:
-			if c.DrawFlag {
-				c.GFX.Draw()
-				c.DrawFlag = false
+			// check it before resetting, so a ROM that won't load leaves the one that's running alone
+			if err := chip.CheckROM(rom); err != nil {
+				log.Print("[ERROR] reload: ", err)
+				return
 			}
-
-		case <-timers.C:
-			// decrement the timers if required
-			if c.DelayTimer > 0 {
-				c.DelayTimer -= 1
+			runner.Do(func(c *chip.Chip8) {
+				c.Reset(true)
+				if err := c.LoadROM(rom); err != nil {
+					// the chip's been wiped, stop like any other fault so the display is closed and the state dumped
+					fail(err)
+				}
+			})
+			notify("reloaded %s", *romPath)
+		},
+		"F5": func() {
+			paused = !paused
+			hud.Paused = paused
+			runner.Pause(paused)
+		},
+		"F6": func() {
+			if speed == 1 {
+				speed = fastForwardSpeed
+			} else {
+				speed = 1
+			}
+			hud.FastForward = speed != 1
+			runner.SetSpeed(speed)
+		},
+		"F12": func() {
+			var overlay *gfx.HUD
+			if *shotHUD {
+				overlay = hud
 			}
-			if c.SoundTimer > 0 {
-				c.SoundTimer -= 1
+			path, err := saveScreenshot(screen, *shotScale, colours, scaler, overlay)
+			if err != nil {
+				log.Print("[ERROR] screenshot: ", err)
+				return
 			}
+			notify("saved screenshot to %s", path)
+		},
+	}
+
+	// F9 starts recording to a GIF, F10 stops and saves it
+	var recorder *gfx.GIFRecorder
+	hotkeys["F9"] = func() {
+		if recorder == nil {
+			recorder = gfx.NewGIFRecorder(*shotScale, colours)
+			recorder.Scaler = scaler
+			notify("recording started")
 		}
 	}
-}
+	hotkeys["F10"] = func() {
+		if recorder == nil {
+			return
+		}
+		path, err := saveRecording(recorder)
+		recorder = nil
+		if err != nil {
+			log.Print("[ERROR] recording: ", err)
+			return
+		}
+		notify("saved recording to %s", path)
+	}
+
+	var display gfx.GFX
+	var closeDisplay func()
+	var events func() bool
+
+	switch *displayName {
+	case "sdl":
+		display, closeDisplay, events, err = newSDLDisplay(sdlOptions{
+			RomPath: *romPath,
+			Palette: colours,
+			Scaler:  scaler,
+			HUD:     hud,
+			Keypad:  runner,
+			Gamepad: settings.Gamepad,
+			Hotkeys: hotkeys,
+
+			OnScreenKeypad: keypad,
+			ShowKeypad:     *showKeypad,
+		})
+		if err != nil {
+			log.Fatal("[ERROR] ", err)
+		}
 
-func processEvents() bool {
-	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-		switch event.(type) {
-		// switch case for when someone quits out of application
-		case *sdl.QuitEvent:
-			log.Println("[DEBUG] Quit") // not necessary
-			// decided with os.Exit since I was having issues when I just
-			//broke the game loop and window wasn't closing properly
-			os.Exit(0)
+	case "terminal":
+		restore, err := input.MakeRaw(os.Stdin.Fd())
+		if err != nil {
+			log.Fatal("[ERROR] terminal input: ", err)
 		}
+		keys := input.NewTerminalKeys(os.Stdin)
+		keys.ReleaseAfter = *keyRelease
+
+		term := gfx.NewTerminalGFX()
+		term.Colour = *colour
+		term.Palette = colours
+		display = term
+		closeDisplay = func() {
+			term.Close()
+			restore()
+		}
+		events = func() bool {
+			return keys.Update(time.Now(), runner)
+		}
+
+	default:
+		log.Fatalf("[ERROR] unknown display: %s", *displayName)
 	}
+	defer closeDisplay()
+
+	// ctrl-c returns from main rather than exiting so the display is closed, leaving the terminal as it was. In raw mode
+	// ctrl-c is read as a key instead, and the terminal input ends the loop.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 
-	return true
+	c.OnIdle = func(s chip.IdleState) {
+		if s == chip.IdleHalted {
+			log.Printf("[INFO] halted at %03x", c.PC)
+		}
+	}
+
+	go func() {
+		defer close(stopped)
+		if err := runner.Run(ctx); err != nil {
+			fail(err)
+		}
+	}()
+	defer func() {
+		stop()
+		<-stopped
+	}()
+
+	for events() {
+		select {
+		case <-ctx.Done():
+			return
+
+		case err := <-failed:
+			// once the runner has stopped the chip is safe to look at
+			stop()
+			<-stopped
+			closeDisplay()
+			c.DiagDump()
+			log.Fatal("[ERROR] ", err)
+
+		case f := <-runner.Frames():
+			screen = f.Display
+			hud.CyclesPerFrame = f.Cycles
+			hud.SetKeys(f.Keypad)
+			keypad.SetKeys(f.Keypad)
+			keypad.Polled = f.KeysPolled
+
+			// a filter blends frames together so it's presented every tick, even when nothing was drawn, and so do
+			// the HUD and keypad to keep them up to date
+			if settings.Filter != nil {
+				display.Present(settings.Filter.Filter(f.Display))
+			} else if f.Drawn || hud.Visible || keypad.Visible {
+				display.Present(f.Display)
+			}
+			if recorder != nil {
+				recorder.AddFrame(f.Display)
+			}
+		}
+	}
 }
@@ -0,0 +1,15 @@
+//go:build !cgo || nosdl
+
+package main
+
+import (
+	"errors"
+
+	"github.com/cuotos/chip8/gfx"
+)
+
+const defaultDisplay = "terminal"
+
+func newSDLDisplay(o sdlOptions) (gfx.GFX, func(), func() bool, error) {
+	return nil, nil, nil, errors.New("built without SDL, rebuild with cgo enabled and without the nosdl tag, or use -display terminal")
+}
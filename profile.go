@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/cuotos/chip8/gfx"
+	"github.com/cuotos/chip8/input"
+	"github.com/cuotos/chip8/profile"
+)
+
+// paletteHelp is the usage of every -palette flag
+func paletteHelp() string {
+	return "colours of the display, a palette name (" + strings.Join(gfx.PaletteNames(), ", ") + ") or hex colours, eg 000000,00ff00. Defaults to the ROM's profile"
+}
+
+// scalerHelp is the usage of every -scaler flag
+func scalerHelp() string {
+	return "how the display is enlarged in the window, screenshots and recordings, " + strings.Join(gfx.ScalerNames(), ", ")
+}
+
+const (
+	filterHelp  = "filter to hide flicker, " + gfx.FilterNames + ". Defaults to the ROM's profile"
+	vblankHelp  = "draw sprites only on the vertical blank, one a frame, as the COSMAC VIP did. Defaults to the ROM's profile"
+	gamepadHelp = "gamepad buttons to hex keys, eg dpup=1,dpdown=4,a=5, anything not set has its default. Defaults to the ROM's profile"
+)
+
+// romSettings are the settings to run a ROM with, from its profile and any flags that override it
+type romSettings struct {
+	Palette     gfx.Palette
+	Filter      gfx.Filter
+	DisplayWait bool
+	Gamepad     input.GamepadMap
+}
+
+// settingsFlags are the -palette, -filter, -gamepad and -vblank flags that override a ROM's profile. A subcommand
+// without one of them leaves it nil.
+type settingsFlags struct {
+	Palette *string
+	Filter  *string
+	Gamepad *string
+	Vblank  *bool
+}
+
+// loadSettings reads the profile for a ROM and overrides it with any of flags that were set on fs
+func loadSettings(fs *flag.FlagSet, flags settingsFlags, romPath string) (romSettings, error) {
+	p, err := profile.Load(romPath)
+	if err != nil {
+		return romSettings{}, err
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if flags.Palette != nil && set["palette"] {
+		p.Palette = *flags.Palette
+	}
+	if flags.Filter != nil && set["filter"] {
+		p.Filter = *flags.Filter
+	}
+	if flags.Gamepad != nil && set["gamepad"] {
+		p.Gamepad = *flags.Gamepad
+	}
+	if flags.Vblank != nil && set["vblank"] {
+		v := *flags.Vblank
+		p.Vblank = &v
+	}
+
+	s := romSettings{
+		Palette: gfx.DefaultPalette,
+	}
+	if p.Palette != "" {
+		if s.Palette, err = gfx.ParsePalette(p.Palette); err != nil {
+			return s, err
+		}
+	}
+	if s.Filter, err = gfx.ParseFilter(p.Filter); err != nil {
+		return s, err
+	}
+	if s.Gamepad, err = input.ParseGamepadMap(p.Gamepad); err != nil {
+		return s, err
+	}
+	if p.Vblank != nil {
+		s.DisplayWait = *p.Vblank
+	}
+
+	return s, nil
+}
@@ -0,0 +1,73 @@
+// Package profile holds the settings that suit a particular ROM. The ROMs that come with the emulator have them built
+// in, any ROM can have them set in a JSON file alongside it with the same name, eg roms/pong.json for roms/pong.ch8.
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Profile is the settings for a ROM, anything not set is left to the defaults
+type Profile struct {
+	Palette string `json:"palette,omitempty"` // a palette name or hex colours, see gfx.ParsePalette
+	Filter  string `json:"filter,omitempty"`  // a display filter to hide flicker, see gfx.ParseFilter
+	Vblank  *bool  `json:"vblank,omitempty"`  // wait for the vertical blank to draw, see chip.Quirks.DisplayWait
+	Gamepad string `json:"gamepad,omitempty"` // gamepad buttons to hex keys, see input.ParseGamepadMap
+}
+
+// Builtin are the profiles for the ROMs in roms/, by file name without the extension
+var Builtin = map[string]Profile{
+	"pong":     {Palette: "classic", Gamepad: "dpup=1,dpdown=4"},
+	"invaders": {Palette: "amber", Filter: "max:2", Gamepad: "dpleft=4,dpright=6,a=5"},
+	"tank":     {Palette: "lcd", Gamepad: "dpup=2,dpdown=8,dpleft=4,dpright=6,a=5"},
+	"blinky":   {Palette: "classic", Filter: "decay:4", Gamepad: "dpup=3,dpdown=6,dpleft=7,dpright=8"},
+}
+
+// Load finds the profile for a ROM, starting with the built in one for its name and overriding that with anything set
+// in the JSON file next to it
+func Load(romPath string) (Profile, error) {
+	ext := filepath.Ext(romPath)
+	base := strings.TrimSuffix(romPath, ext)
+
+	p := Builtin[filepath.Base(base)]
+
+	sidecar := base + ".json"
+	b, err := ioutil.ReadFile(sidecar)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+
+	var override Profile
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&override); err != nil {
+		return p, fmt.Errorf("reading profile %s: %w", sidecar, err)
+	}
+	p.merge(override)
+
+	return p, nil
+}
+
+// merge overrides p with everything set in o
+func (p *Profile) merge(o Profile) {
+	if o.Palette != "" {
+		p.Palette = o.Palette
+	}
+	if o.Filter != "" {
+		p.Filter = o.Filter
+	}
+	if o.Vblank != nil {
+		p.Vblank = o.Vblank
+	}
+	if o.Gamepad != "" {
+		p.Gamepad = o.Gamepad
+	}
+}
@@ -0,0 +1,53 @@
+package profile
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/cuotos/chip8/input"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBuiltin(t *testing.T) {
+	p, err := Load("../roms/invaders.ch8")
+	require.NoError(t, err)
+	assert.Equal(t, Builtin["invaders"], p)
+
+	p, err = Load("../roms/test_rom.ch8")
+	require.NoError(t, err)
+	assert.Equal(t, Profile{}, p)
+}
+
+func TestBuiltinGamepadMaps(t *testing.T) {
+	for name, p := range Builtin {
+		_, err := input.ParseGamepadMap(p.Gamepad)
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestLoadSidecar(t *testing.T) {
+	dir := t.TempDir()
+
+	// a sidecar overrides the built in profile of a ROM with the same name
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invaders.json"), []byte(`{"palette": "lcd", "vblank": false, "gamepad": "a=0"}`), 0644))
+	p, err := Load(filepath.Join(dir, "invaders.ch8"))
+	require.NoError(t, err)
+	assert.Equal(t, "lcd", p.Palette)
+	assert.Equal(t, "max:2", p.Filter)
+	assert.Equal(t, "a=0", p.Gamepad)
+	if assert.NotNil(t, p.Vblank) {
+		assert.False(t, *p.Vblank)
+	}
+
+	// anything the sidecar doesn't set is left alone
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tank.json"), []byte(`{}`), 0644))
+	p, err = Load(filepath.Join(dir, "tank.ch8"))
+	require.NoError(t, err)
+	assert.Equal(t, Builtin["tank"], p)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "typo.json"), []byte(`{"pallete": "lcd"}`), 0644))
+	_, err = Load(filepath.Join(dir, "typo.ch8"))
+	assert.Error(t, err)
+}
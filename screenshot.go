@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cuotos/chip8/gfx"
+)
+
+// capturePath names a screenshot or recording after the time it was taken, in the current directory
+func capturePath(ext string) string {
+	return fmt.Sprintf("chip8-%s.%s", time.Now().Format("20060102-150405.000"), ext)
+}
+
+// saveScreenshot writes the display to a PNG, with the HUD over it if one is given and it's showing
+func saveScreenshot(f *gfx.Framebuffer, scale int, p gfx.Palette, s gfx.Scaler, hud *gfx.HUD) (string, error) {
+	path := capturePath("png")
+	if hud != nil && hud.Visible {
+		return path, gfx.SaveImagePNG(path, hud.Overlay(f, scale, p, s, time.Now()))
+	}
+	return path, gfx.SavePNG(path, f, scale, p, s)
+}
+
+// saveRecording writes everything recorded to a GIF
+func saveRecording(r *gfx.GIFRecorder) (string, error) {
+	path := capturePath("gif")
+	return path, r.Save(path)
+}
@@ -0,0 +1,161 @@
+//go:build cgo && !nosdl
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cuotos/chip8/gfx"
+	"github.com/cuotos/chip8/gfx/sdlgfx"
+	"github.com/cuotos/chip8/input"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const defaultDisplay = "sdl"
+
+// newSDLDisplay opens the window, titled with the ROM's name, returning it along with functions to close it and to poll
+// it for events. F2 and F11 are added to the hotkeys, to toggle the on screen keypad and fullscreen.
+func newSDLDisplay(o sdlOptions) (gfx.GFX, func(), func() bool, error) {
+	display, err := sdlgfx.New(gfx.Width, gfx.Height, 10)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	display.Palette = o.Palette
+	display.Scaler = o.Scaler
+	display.HUD = o.HUD
+	display.Title = "chip8 - " + strings.TrimSuffix(filepath.Base(o.RomPath), filepath.Ext(o.RomPath))
+	o.Hotkeys["F11"] = display.ToggleFullscreen
+	display.Keypad = o.OnScreenKeypad
+	o.Hotkeys["F2"] = func() {
+		display.ShowKeypad(!o.OnScreenKeypad.Visible)
+	}
+	display.ShowKeypad(o.ShowKeypad)
+
+	ev := &sdlEvents{
+		display:     display,
+		hotkeys:     o.Hotkeys,
+		keypad:      o.Keypad,
+		gamepads:    input.NewGamepads(o.Gamepad),
+		controllers: map[sdl.JoystickID]*sdl.GameController{},
+	}
+
+	return display, ev.close, ev.process, nil
+}
+
+// sdlEvents handles everything that happens in the window, and the game controllers
+type sdlEvents struct {
+	display     *sdlgfx.Graphics
+	hotkeys     map[string]func()
+	keypad      input.Keypad
+	gamepads    *input.Gamepads
+	controllers map[sdl.JoystickID]*sdl.GameController // open controllers, by instance id
+
+	clicked  uint8 // the key of the on screen keypad held down with the mouse, if clicking
+	clicking bool
+}
+
+func (ev *sdlEvents) process() bool {
+	ev.display.UpdateTitle(time.Now())
+
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch e := event.(type) {
+		// switch case for when someone quits out of application
+		case *sdl.QuitEvent:
+			log.Println("[DEBUG] Quit") // not necessary
+			// decided with os.Exit since I was having issues when I just
+			//broke the game loop and window wasn't closing properly
+			os.Exit(0)
+
+		case *sdl.WindowEvent:
+			// the window is drawn again straight away rather than waiting for the ROM to draw
+			if e.Event == sdl.WINDOWEVENT_SIZE_CHANGED || e.Event == sdl.WINDOWEVENT_EXPOSED {
+				ev.display.Redraw()
+			}
+
+		case *sdl.KeyboardEvent:
+			if e.Type != sdl.KEYDOWN || e.Repeat != 0 {
+				continue
+			}
+			if f, ok := ev.hotkeys[sdl.GetKeyName(e.Keysym.Sym)]; ok {
+				f()
+			}
+
+		case *sdl.MouseButtonEvent:
+			ev.mouseButton(e)
+
+		case *sdl.ControllerDeviceEvent:
+			ev.controllerDevice(e)
+
+		case *sdl.ControllerButtonEvent:
+			button := sdl.GameControllerGetStringForButton(sdl.GameControllerButton(e.Button))
+			ev.gamepads.Button(int(e.Which), button, e.State == sdl.PRESSED, ev.keypad)
+		}
+	}
+
+	return true
+}
+
+// mouseButton presses a key of the on screen keypad while the left button is held on it. Touches come as mouse events
+// too.
+func (ev *sdlEvents) mouseButton(e *sdl.MouseButtonEvent) {
+	if e.Button != sdl.BUTTON_LEFT {
+		return
+	}
+
+	if ev.clicking {
+		ev.keypad.SetKey(ev.clicked, false)
+		ev.clicking = false
+	}
+	if e.Type != sdl.MOUSEBUTTONDOWN {
+		return
+	}
+	if key, ok := ev.display.KeyAt(e.X, e.Y); ok {
+		ev.keypad.SetKey(key, true)
+		ev.clicked, ev.clicking = key, true
+	}
+}
+
+// controllerDevice opens controllers as they're plugged in, including any already plugged in when the window opens,
+// and closes them when they're unplugged
+func (ev *sdlEvents) controllerDevice(e *sdl.ControllerDeviceEvent) {
+	switch e.Type {
+	case sdl.CONTROLLERDEVICEADDED:
+		// Which is the device index when it's added
+		gc := sdl.GameControllerOpen(int(e.Which))
+		if gc == nil {
+			log.Printf("[ERROR] opening game controller %d: %s", e.Which, sdl.GetError())
+			return
+		}
+		id := gc.Joystick().InstanceID()
+		if _, open := ev.controllers[id]; open {
+			gc.Close()
+			return
+		}
+		ev.controllers[id] = gc
+		log.Printf("[INFO] game controller connected: %s", gc.Name())
+
+	case sdl.CONTROLLERDEVICEREMOVED:
+		// and the instance id when it's removed
+		gc, ok := ev.controllers[e.Which]
+		if !ok {
+			return
+		}
+		ev.gamepads.Remove(int(e.Which), ev.keypad)
+		log.Printf("[INFO] game controller disconnected: %s", gc.Name())
+		gc.Close()
+		delete(ev.controllers, e.Which)
+	}
+}
+
+// close closes the controllers and the window
+func (ev *sdlEvents) close() {
+	for id, gc := range ev.controllers {
+		gc.Close()
+		delete(ev.controllers, id)
+	}
+	ev.display.Cleanup()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/cuotos/chip8/testrom"
+)
+
+// runTestROM is the testrom subcommand: chip8 testrom [-frames N] [-suite name] rom...
+// It exits non-zero if any test in any of the ROMs failed.
+func runTestROM(args []string) int {
+	fs := flag.NewFlagSet("testrom", flag.ExitOnError)
+	frames := fs.Int("frames", testrom.DefaultMaxFrames, "give up if the ROM hasn't finished after this many frames")
+	suiteName := fs.String("suite", "", "the test suite the ROM contains, guessed from the file name if not set")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Println("usage: chip8 testrom [-frames N] [-suite name] <rom>...")
+		return 2
+	}
+
+	status := 0
+	for _, path := range fs.Args() {
+		var suite testrom.Suite
+		var err error
+		if *suiteName != "" {
+			var ok bool
+			if suite, ok = testrom.Suites[*suiteName]; !ok {
+				err = fmt.Errorf("unknown test suite: %s", *suiteName)
+			}
+		} else {
+			suite, err = testrom.SuiteFor(path)
+		}
+		if err != nil {
+			log.Print("[ERROR] ", err)
+			return 2
+		}
+
+		report, err := testrom.Run(path, suite, *frames)
+		if err != nil {
+			log.Print("[ERROR] ", err)
+			if report != nil {
+				fmt.Print(report.Screen)
+			}
+			status = 1
+			continue
+		}
+
+		fmt.Print(report)
+		if !report.Passed() {
+			fmt.Print(report.Screen)
+			status = 1
+		}
+	}
+
+	return status
+}
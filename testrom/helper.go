@@ -0,0 +1,32 @@
+package testrom
+
+import "testing"
+
+// Check runs a test ROM, picking the suite from its file name, and fails the test for every result that didn't pass
+func Check(t testing.TB, path string) *Report {
+	t.Helper()
+
+	suite, err := SuiteFor(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(path, suite, DefaultMaxFrames)
+	if err != nil {
+		if report != nil {
+			t.Logf("screen:\n%s", report.Screen)
+		}
+		t.Fatal(err)
+	}
+
+	for _, res := range report.Results {
+		if !res.Passed {
+			t.Errorf("%s: %s failed %s", path, res.Name, res.Detail)
+		}
+	}
+	if !report.Passed() {
+		t.Logf("screen:\n%s", report.Screen)
+	}
+
+	return report
+}
@@ -0,0 +1,102 @@
+package testrom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cuotos/chip8/chip"
+)
+
+// "BON" is drawn in the middle of the screen by BC_test once every test has passed
+var bonGlyph = []string{
+	"####.....####...#....#",
+	"#...#...#....#..##...#",
+	"#...#...#....#..#.#..#",
+	"####....#....#..#..#.#",
+	"#...#...#....#..#...##",
+	"#...#...#....#..#....#",
+	"#...#...#....#..#....#",
+	"####.....####...#....#",
+}
+
+// BC_test stops at the first failing test and shows its error number instead of BON
+var bcTest = Suite{
+	Name: "bc_test",
+	Check: func(s Screen) []Result {
+		if s.Match(21, 11, bonGlyph) {
+			return []Result{{Name: "all", Passed: true}}
+		}
+
+		detail := "BON not shown"
+		if digits := hexDigits(s); digits != "" {
+			detail = fmt.Sprintf("error %s", digits)
+		}
+		return []Result{{Name: "all", Passed: false, Detail: detail}}
+	},
+}
+
+// corax89's test_opcode draws a 3x6 grid of "<opcode> OK" labels, anything other than OK next to a label is a failure
+var okGlyph = []string{
+	"###.#.#",
+	"#.#.##.",
+	"#.#.#.#",
+	"###.#.#",
+}
+
+var testOpcodeGrid = [3][6]string{
+	{"3XNN", "4XNN", "5XY0", "7XNN", "9XY0", "ANNN"},
+	{"00EE", "8XY0", "8XY1", "8XY2", "8XY3", "8XY4"},
+	{"8XY5", "8XY6", "8XYE", "FX55", "FX33", "1NNN"},
+}
+
+// x of the OK for each column of the grid
+var testOpcodeColumns = [3]int{10, 32, 52}
+
+var testOpcode = Suite{
+	Name: "test_opcode",
+	Check: func(s Screen) []Result {
+		var results []Result
+		for col, names := range testOpcodeGrid {
+			for row, name := range names {
+				results = append(results, Result{
+					Name:   name,
+					Passed: s.Match(testOpcodeColumns[col], 1+row*5, okGlyph),
+				})
+			}
+		}
+		return results
+	},
+}
+
+// hexDigits reads any characters from the built in font on the screen, left to right and top to bottom
+func hexDigits(s Screen) string {
+	glyphs := make([][]string, 16)
+	for d := range glyphs {
+		for _, b := range chip.FontSet[d*5 : d*5+5] {
+			row := ""
+			for bit := uint(0); bit < 4; bit++ {
+				if b&(0x80>>bit) != 0 {
+					row += "#"
+				} else {
+					row += "."
+				}
+			}
+			glyphs[d] = append(glyphs[d], row)
+		}
+	}
+
+	var digits []string
+	for y := 0; y < s.f.Height; y++ {
+		for x := 0; x < s.f.Width; x++ {
+			for d, g := range glyphs {
+				if s.Match(x, y, g) {
+					digits = append(digits, fmt.Sprintf("%X", d))
+					x += 3
+					break
+				}
+			}
+		}
+	}
+
+	return strings.Join(digits, "")
+}
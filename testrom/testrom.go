@@ -0,0 +1,148 @@
+// Package testrom runs the community CHIP-8 test ROMs headlessly and reads their pass/fail results off the screen.
+package testrom
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/cuotos/chip8/chip"
+	"github.com/cuotos/chip8/gfx"
+)
+
+// DefaultMaxFrames is how long a ROM gets to reach its idle loop before giving up, 10 seconds of emulated time
+const DefaultMaxFrames = 600
+
+// Result is the outcome of a single test within a test ROM
+type Result struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// Report is everything read off the screen once the ROM finished
+type Report struct {
+	ROM     string
+	Suite   string
+	Cycles  uint64
+	Results []Result
+	Screen  string // text art of the final frame, for when a result can't be read
+}
+
+func (r *Report) Passed() bool {
+	if len(r.Results) == 0 {
+		return false
+	}
+	for _, res := range r.Results {
+		if !res.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Report) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s (%s) after %d cycles:\n", r.ROM, r.Suite, r.Cycles)
+	for _, res := range r.Results {
+		status := "ok"
+		if !res.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(b, "  %-6s %s", status, res.Name)
+		if res.Detail != "" {
+			fmt.Fprintf(b, ": %s", res.Detail)
+		}
+		fmt.Fprintln(b)
+	}
+	return b.String()
+}
+
+// Screen gives a suite read access to the final frame
+type Screen struct {
+	f *gfx.Framebuffer
+}
+
+func (s Screen) Pixel(x, y int) bool {
+	return s.f.Get(x, y)
+}
+
+// Match checks the pixels at x, y are exactly the glyph, given as rows of '#' and '.'
+func (s Screen) Match(x, y int, glyph []string) bool {
+	for row, line := range glyph {
+		for col, ch := range line {
+			if s.Pixel(x+col, y+row) != (ch == '#') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s Screen) String() string {
+	return s.f.String()
+}
+
+// Suite knows how to read the results of one test ROM off the screen
+type Suite struct {
+	Name  string
+	Check func(s Screen) []Result
+}
+
+// Suites are the known test ROMs, by the name of the ROM file without its extension
+var Suites = map[string]Suite{
+	"bc_test":     bcTest,
+	"test_opcode": testOpcode,
+}
+
+// SuiteFor picks the suite for a ROM from its file name
+func SuiteFor(path string) (Suite, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	s, ok := Suites[name]
+	if !ok {
+		return Suite{}, fmt.Errorf("no known test suite for %s", path)
+	}
+	return s, nil
+}
+
+// Run loads a test ROM and runs it until it halts, which the test ROMs do with a jump to itself once they've finished,
+// then reads the results off the screen.
+func Run(path string, suite Suite, maxFrames int) (*Report, error) {
+	rom, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := chip.NewDefaultChip()
+	c.Initialise()
+	if err := c.LoadROM(rom); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		ROM:   path,
+		Suite: suite.Name,
+	}
+
+	for frame := 0; frame < maxFrames && c.Idle != chip.IdleHalted; frame++ {
+		for i := 0; i < chip.CyclesPerFrame && c.Idle != chip.IdleHalted; i++ {
+			if err := c.EmulateCycle(); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		c.TickTimers()
+	}
+
+	screen := Screen{c.Display}
+	report.Cycles = c.Cycles
+	report.Screen = screen.String()
+
+	if c.Idle != chip.IdleHalted {
+		return report, fmt.Errorf("%s did not finish within %d frames", path, maxFrames)
+	}
+
+	report.Results = suite.Check(screen)
+
+	return report, nil
+}
@@ -0,0 +1,57 @@
+package testrom
+
+import (
+	"testing"
+
+	"github.com/cuotos/chip8/chip"
+	"github.com/cuotos/chip8/gfx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBCTest(t *testing.T) {
+	report := Check(t, "../roms/bc_test.ch8")
+	assert.Len(t, report.Results, 1)
+}
+
+func TestOpcodeTestROM(t *testing.T) {
+	report := Check(t, "../test_opcode.ch8")
+	assert.Len(t, report.Results, 18)
+}
+
+// draw a character from the built in font at x, y
+func drawDigit(f *gfx.Framebuffer, x, y int, d int) {
+	for row, b := range chip.FontSet[d*5 : d*5+5] {
+		for col := 0; col < 4; col++ {
+			if b&(0x80>>uint(col)) != 0 {
+				f.Set(x+col, y+row, true)
+			}
+		}
+	}
+}
+
+func TestSuitesReportFailures(t *testing.T) {
+	display := gfx.NewFramebuffer(gfx.Width, gfx.Height)
+	drawDigit(display, 10, 10, 0xe)
+	drawDigit(display, 16, 10, 0x3)
+	screen := Screen{display}
+
+	results := bcTest.Check(screen)
+	if assert.Len(t, results, 1) {
+		assert.False(t, results[0].Passed)
+		assert.Equal(t, "error E3", results[0].Detail)
+	}
+
+	for _, res := range testOpcode.Check(screen) {
+		assert.False(t, res.Passed, res.Name)
+	}
+}
+
+func TestSuiteFor(t *testing.T) {
+	s, err := SuiteFor("/some/where/test_opcode.ch8")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "test_opcode", s.Name)
+	}
+
+	_, err = SuiteFor("roms/pong.ch8")
+	assert.Error(t, err)
+}
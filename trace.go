@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cuotos/chip8/chip"
+)
+
+// openTrace creates the trace file and a tracer for it from the command line flags
+func openTrace(path, format, pcRange, cycleRange string) (*chip.Tracer, *os.File, error) {
+	var tf chip.TraceFormat
+	switch format {
+	case "jsonl", "json":
+		tf = chip.TraceJSON
+	case "binary", "bin":
+		tf = chip.TraceBinary
+	default:
+		return nil, nil, fmt.Errorf("unknown trace format: %s", format)
+	}
+
+	var filter chip.TraceFilter
+	if pcRange != "" {
+		start, end, err := parseRange(pcRange, 16)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid PC range: %w", err)
+		}
+		filter.PCStart, filter.PCEnd = uint16(start), uint16(end)
+	}
+	if cycleRange != "" {
+		start, end, err := parseRange(cycleRange, 10)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid cycle range: %w", err)
+		}
+		filter.CycleStart, filter.CycleEnd = start, end
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracer := chip.NewTracer(f, tf)
+	tracer.Filter = filter
+
+	return tracer, f, nil
+}
+
+// parseRange reads "start-end", either side can be left empty
+func parseRange(s string, base int) (uint64, uint64, error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected start-end, got %q", s)
+	}
+
+	var start, end uint64
+	var err error
+	if parts[0] != "" {
+		start, err = strconv.ParseUint(strings.TrimPrefix(parts[0], "0x"), base, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if parts[1] != "" {
+		end, err = strconv.ParseUint(strings.TrimPrefix(parts[1], "0x"), base, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return start, end, nil
+}
+
+// runTraceDiff is the tracediff subcommand: chip8 tracediff a.trace b.trace
+func runTraceDiff(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: chip8 tracediff <trace a> <trace b>")
+		return 2
+	}
+
+	same, err := traceDiff(args[0], args[1])
+	if err != nil {
+		log.Print("[ERROR] ", err)
+		return 2
+	}
+	if !same {
+		return 1
+	}
+	return 0
+}
+
+// traceDiff compares two trace files and prints where they first differ. It returns false if they do.
+func traceDiff(pathA, pathB string) (bool, error) {
+	fa, err := os.Open(pathA)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+
+	fb, err := os.Open(pathB)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	a, err := chip.NewTraceReader(fa)
+	if err != nil {
+		return false, err
+	}
+	b, err := chip.NewTraceReader(fb)
+	if err != nil {
+		return false, err
+	}
+
+	d, err := chip.DiffTraces(a, b)
+	if err != nil {
+		return false, err
+	}
+	if d != nil {
+		fmt.Println(d)
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -3,3 +3,10 @@ package utils
 import "errors"
 
 var UnkownOpcode = errors.New("unknown opcode")
+
+// Faults raised by the chip when a ROM does something the hardware can't
+var (
+	MemoryOutOfRange = errors.New("memory access out of range")
+	StackOverflow    = errors.New("stack overflow")
+	StackUnderflow   = errors.New("stack underflow")
+)